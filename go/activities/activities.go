@@ -0,0 +1,12 @@
+package activities
+
+// Activities holds the activities that depend on external services. Register a
+// configured instance with the worker and reference its methods through a nil
+// *Activities in workflows.
+type Activities struct {
+	Payments  PaymentGateway
+	Inventory InventoryService
+	Carrier   Carrier
+	// Warehouses routes orders to warehouses, DefaultWarehouses if nil
+	Warehouses Warehouses
+}
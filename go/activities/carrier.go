@@ -0,0 +1,263 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"temporal-order-management/app"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+)
+
+// ErrUnknownShipment is returned for shipments the carrier is not delivering.
+var ErrUnknownShipment = errors.New("unknown shipment")
+
+// Carrier delivers shipped orders. It confirms each delivery later, by
+// completing the AwaitCarrierConfirmation activity with the shipment's task
+// token, like a carrier calling a webhook.
+type Carrier interface {
+	RegisterShipment(ctx context.Context, shipment CarrierShipment) error
+}
+
+// CarrierShipment is a shipment awaiting delivery.
+type CarrierShipment struct {
+	ShipmentId string `json:"shipmentId"`
+	OrderId    string `json:"orderId"`
+	Address    string `json:"address"`
+	// TaskToken completes the AwaitCarrierConfirmation activity
+	TaskToken []byte `json:"taskToken"`
+}
+
+// DeliveryFailed is the error type of deliveries the carrier gave up on.
+const DeliveryFailed = "DeliveryFailed"
+
+// AwaitCarrierConfirmation hands a shipment of the order to the carrier and
+// completes once the carrier confirms its delivery, which can take days. The
+// activity returns without a result and the carrier completes it with the task
+// token.
+func (a *Activities) AwaitCarrierConfirmation(ctx context.Context, input app.ShippingInput) (app.DeliveryConfirmation, error) {
+	info := activity.GetInfo(ctx)
+	var shipmentId string
+	if input.Shipment != nil {
+		shipmentId = input.Shipment.Id
+	}
+	shipment := CarrierShipment{
+		ShipmentId: app.CarrierShipmentId(input.Order.OrderId, shipmentId),
+		OrderId:    input.Order.OrderId,
+		Address:    input.Order.Address,
+		TaskToken:  info.TaskToken,
+	}
+	// a retry registers the shipment again, with the token of the new attempt
+	err := a.Carrier.RegisterShipment(ctx, shipment)
+	if err != nil {
+		return app.DeliveryConfirmation{}, err
+	}
+	activity.GetLogger(ctx).Info("Awaiting carrier confirmation", "shipmentId", shipment.ShipmentId, "attempt", info.Attempt)
+	return app.DeliveryConfirmation{}, activity.ErrResultPending
+}
+
+// CarrierSimulator is a Carrier that keeps shipments in memory and confirms
+// them through its webhooks, or by itself after AutoDeliver.
+type CarrierSimulator struct {
+	client client.Client
+	// AutoDeliver delivers shipments after this long if not zero
+	AutoDeliver time.Duration
+
+	mu        sync.Mutex
+	shipments map[string]CarrierShipment
+}
+
+// NewCarrierSimulator creates a CarrierSimulator completing activities with c.
+func NewCarrierSimulator(c client.Client, autoDeliver time.Duration) *CarrierSimulator {
+	return &CarrierSimulator{
+		client:      c,
+		AutoDeliver: autoDeliver,
+		shipments:   map[string]CarrierShipment{},
+	}
+}
+
+func (s *CarrierSimulator) RegisterShipment(ctx context.Context, shipment CarrierShipment) error {
+	s.mu.Lock()
+	s.shipments[shipment.ShipmentId] = shipment
+	s.mu.Unlock()
+
+	if s.AutoDeliver > 0 {
+		time.AfterFunc(s.AutoDeliver, func() {
+			err := s.Deliver(context.Background(), shipment.ShipmentId, "")
+			if err != nil && !errors.Is(err, ErrUnknownShipment) {
+				log.Println("Unable to confirm delivery", shipment.ShipmentId, err)
+			}
+		})
+	}
+	return nil
+}
+
+// Shipments returns the shipments awaiting delivery.
+func (s *CarrierSimulator) Shipments() []CarrierShipment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shipments := make([]CarrierShipment, 0, len(s.shipments))
+	for _, shipment := range s.shipments {
+		shipments = append(shipments, shipment)
+	}
+	return shipments
+}
+
+// Deliver confirms delivery of a shipment, signed for by receivedBy.
+func (s *CarrierSimulator) Deliver(ctx context.Context, shipmentId string, receivedBy string) error {
+	shipment, err := s.take(shipmentId)
+	if err != nil {
+		return err
+	}
+	confirmation := app.DeliveryConfirmation{
+		ShipmentId:  shipmentId,
+		DeliveredAt: time.Now().UTC(),
+		ReceivedBy:  receivedBy,
+	}
+	return s.complete(ctx, shipment, confirmation, nil)
+}
+
+// Fail reports that the carrier gave up on delivering a shipment.
+func (s *CarrierSimulator) Fail(ctx context.Context, shipmentId string, reason string) error {
+	shipment, err := s.take(shipmentId)
+	if err != nil {
+		return err
+	}
+	if reason == "" {
+		reason = "delivery failed"
+	}
+	return s.complete(ctx, shipment, nil, temporal.NewNonRetryableApplicationError(reason, DeliveryFailed, nil))
+}
+
+func (s *CarrierSimulator) take(shipmentId string) (CarrierShipment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shipment, ok := s.shipments[shipmentId]
+	if !ok {
+		return CarrierShipment{}, fmt.Errorf("%w: %v", ErrUnknownShipment, shipmentId)
+	}
+	delete(s.shipments, shipmentId)
+	return shipment, nil
+}
+
+func (s *CarrierSimulator) complete(ctx context.Context, shipment CarrierShipment, result any, err error) error {
+	completeErr := s.client.CompleteActivity(ctx, shipment.TaskToken, result, err)
+	if completeErr != nil {
+		// keep the shipment, so the webhook can be retried
+		s.mu.Lock()
+		s.shipments[shipment.ShipmentId] = shipment
+		s.mu.Unlock()
+	}
+	return completeErr
+}
+
+type deliveryRequest struct {
+	ReceivedBy string `json:"receivedBy"`
+}
+
+type deliveryFailedRequest struct {
+	Reason string `json:"reason"`
+}
+
+// Handler serves the HTTPCarrier protocol and the delivery webhooks.
+func (s *CarrierSimulator) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /shipments", func(w http.ResponseWriter, r *http.Request) {
+		var shipment CarrierShipment
+		if err := json.NewDecoder(r.Body).Decode(&shipment); err != nil || shipment.ShipmentId == "" {
+			http.Error(w, "invalid shipment", http.StatusBadRequest)
+			return
+		}
+		s.RegisterShipment(r.Context(), shipment)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	mux.HandleFunc("GET /shipments", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Shipments())
+	})
+
+	mux.HandleFunc("POST /shipments/{id}/delivered", func(w http.ResponseWriter, r *http.Request) {
+		var req deliveryRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		writeCarrierError(w, s.Deliver(r.Context(), r.PathValue("id"), req.ReceivedBy))
+	})
+
+	mux.HandleFunc("POST /shipments/{id}/failed", func(w http.ResponseWriter, r *http.Request) {
+		var req deliveryFailedRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		writeCarrierError(w, s.Fail(r.Context(), r.PathValue("id"), req.Reason))
+	})
+
+	return mux
+}
+
+func writeCarrierError(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusOK)
+	case errors.Is(err, ErrUnknownShipment):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	}
+}
+
+// HTTPCarrier is a Carrier talking JSON over HTTP, e.g. to the simulator
+// served by CarrierSimulator.Handler.
+type HTTPCarrier struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewHTTPCarrier(baseURL string) *HTTPCarrier {
+	return &HTTPCarrier{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (c *HTTPCarrier) RegisterShipment(ctx context.Context, shipment CarrierShipment) error {
+	var body bytes.Buffer
+	err := json.NewEncoder(&body).Encode(shipment)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/shipments", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("carrier returned %v: %v", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
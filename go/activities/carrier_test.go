@@ -0,0 +1,99 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"temporal-order-management/app"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// completingClient records the activities completed through it.
+type completingClient struct {
+	client.Client
+	tokens  []string
+	results []any
+	errs    []error
+}
+
+func (c *completingClient) CompleteActivity(ctx context.Context, taskToken []byte, result interface{}, err error) error {
+	c.tokens = append(c.tokens, string(taskToken))
+	c.results = append(c.results, result)
+	c.errs = append(c.errs, err)
+	return nil
+}
+
+func TestCarrierSimulatorCompletesActivities(t *testing.T) {
+	c := &completingClient{}
+	simulator := NewCarrierSimulator(c, 0)
+	server := httptest.NewServer(simulator.Handler())
+	defer server.Close()
+
+	carrier := NewHTTPCarrier(server.URL)
+	ctx := context.Background()
+	require.NoError(t, carrier.RegisterShipment(ctx, CarrierShipment{ShipmentId: "1", TaskToken: []byte("token-1")}))
+	require.NoError(t, carrier.RegisterShipment(ctx, CarrierShipment{ShipmentId: "2", TaskToken: []byte("token-2")}))
+	require.Len(t, simulator.Shipments(), 2)
+
+	resp, err := http.Post(server.URL+"/shipments/1/delivered", "application/json", strings.NewReader(`{"receivedBy": "J. Doe"}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, err = http.Post(server.URL+"/shipments/2/failed", "application/json", strings.NewReader(`{"reason": "address not found"}`))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	require.Equal(t, []string{"token-1", "token-2"}, c.tokens)
+	confirmation := c.results[0].(app.DeliveryConfirmation)
+	require.Equal(t, "1", confirmation.ShipmentId)
+	require.Equal(t, "J. Doe", confirmation.ReceivedBy)
+	require.NoError(t, c.errs[0])
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(c.errs[1], &appErr))
+	require.Equal(t, DeliveryFailed, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+	require.Empty(t, simulator.Shipments())
+
+	// each shipment is confirmed once
+	resp, err = http.Post(server.URL+"/shipments/1/delivered", "application/json", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// recordingCarrier records the shipments registered with it.
+type recordingCarrier struct {
+	shipments []CarrierShipment
+}
+
+func (c *recordingCarrier) RegisterShipment(ctx context.Context, shipment CarrierShipment) error {
+	c.shipments = append(c.shipments, shipment)
+	return nil
+}
+
+func TestAwaitCarrierConfirmationRegistersEachShipment(t *testing.T) {
+	var ts testsuite.WorkflowTestSuite
+	env := ts.NewTestActivityEnvironment()
+	carrier := &recordingCarrier{}
+	a := &Activities{Carrier: carrier}
+	env.RegisterActivity(a)
+
+	order := app.OrderInput{OrderId: "123456", Address: "123 Main St"}
+	for _, shipment := range []*app.Shipment{{Id: "east"}, {Id: "west"}, nil} {
+		_, err := env.ExecuteActivity(a.AwaitCarrierConfirmation, app.ShippingInput{Order: order, Shipment: shipment})
+		require.ErrorIs(t, err, activity.ErrResultPending)
+	}
+
+	var ids []string
+	for _, shipment := range carrier.shipments {
+		require.Equal(t, "123456", shipment.OrderId)
+		ids = append(ids, shipment.ShipmentId)
+	}
+	require.Equal(t, []string{"123456-east", "123456-west", "123456"}, ids)
+}
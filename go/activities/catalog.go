@@ -0,0 +1,63 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// CatalogEntry describes a product that can be ordered.
+type CatalogEntry struct {
+	Id          int    `json:"id"`
+	Sku         string `json:"sku"`
+	Description string `json:"description"`
+	UnitPrice   int64  `json:"unitPrice"`
+}
+
+// Catalog is the source GetItems resolves order lines against.
+type Catalog interface {
+	Lookup(ctx context.Context, sku string) (CatalogEntry, bool, error)
+}
+
+// MemoryCatalog is a Catalog backed by a map keyed by SKU.
+type MemoryCatalog map[string]CatalogEntry
+
+func (m MemoryCatalog) Lookup(ctx context.Context, sku string) (CatalogEntry, bool, error) {
+	entry, ok := m[sku]
+	return entry, ok, nil
+}
+
+// DefaultCatalog holds the items the demo has always shipped.
+var DefaultCatalog = MemoryCatalog{
+	"TBL-TOP":  {Id: 654300, Sku: "TBL-TOP", Description: "Table Top", UnitPrice: 12900},
+	"TBL-LEGS": {Id: 654321, Sku: "TBL-LEGS", Description: "Table Legs", UnitPrice: 2450},
+	"KEYPAD":   {Id: 654322, Sku: "KEYPAD", Description: "Keypad", UnitPrice: 3999},
+}
+
+var catalog Catalog = DefaultCatalog
+
+// SetCatalog replaces the catalog used by GetItems. Call it before the worker starts.
+func SetCatalog(c Catalog) {
+	catalog = c
+}
+
+// LoadCatalogFile reads a JSON array of catalog entries.
+func LoadCatalogFile(path string) (MemoryCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CatalogEntry
+	err = json.Unmarshal(data, &entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse catalog %v: %w", path, err)
+	}
+
+	c := MemoryCatalog{}
+	for _, entry := range entries {
+		c[entry.Sku] = entry
+	}
+	return c, nil
+}
@@ -9,41 +9,89 @@ import (
 	"go.temporal.io/sdk/temporal"
 )
 
-const (
-	ErrorChargeAPIUnavailable = "OrderWorkflowAPIFailure"
-	ErrorInvalidCreditCard    = "OrderWorkflowNonRecoverableFailure"
-)
-
-func ChargeCustomer(ctx context.Context, input app.OrderInput, name string) (string, error) {
+// ChargeCustomer authorizes the order amount and returns the authorization id.
+// The payment is captured by CapturePayment once the order has shipped. name
+// is the workflow type of the order.
+func (a *Activities) ChargeCustomer(ctx context.Context, input app.OrderInput, amount int64, name string) (string, error) {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Charge Customer activity started", "orderId", input.OrderId)
+	logger.Info("Charge Customer activity started", "orderId", input.OrderId, "amount", amount, "name", name)
 	attempt := activity.GetInfo(ctx).Attempt
 
-	// simulate external API call
-	error := simulateExternalOperationWithError(1000, name, attempt)
-	logger.Info("Simulated call complete", "name", name, "error", error)
-
-	switch error {
-	case ErrorChargeAPIUnavailable:
-		// a transient error, which can be retried
-		logger.Info("Charge Customer API unavailable", "attempt", attempt)
-		return "", errors.New("charge customer activity failed, API unavailable")
-	case ErrorInvalidCreditCard:
-		// a business error, which cannot be retried
-		return "", temporal.NewNonRetryableApplicationError("charge customer activity failed", "activityFailure", errors.New("credit card invalid"))
-	default:
-		// pass through, no error
+	// simulate external API call, which gets faster as it recovers
+	simulateExternalOperation(1000 / int(attempt))
+	if err := scenarioFailure(ctx, input, "ChargeCustomer"); err != nil {
+		recordChargeFailure(ctx, input.Scenario.Failure.Type)
+		return "", err
+	}
+
+	authorizationId, err := a.Payments.Authorize(ctx, input.OrderId, input.PaymentRef, amount)
+	if err != nil {
+		if errors.Is(err, ErrPaymentDeclined) {
+			recordChargeFailure(ctx, "declined")
+		} else {
+			recordChargeFailure(ctx, "gatewayError")
+		}
+		return "", paymentError("charge customer activity failed", err)
+	}
+	logger.Info("Payment authorized", "orderId", input.OrderId, "authorizationId", authorizationId)
+
+	return authorizationId, nil
+}
+
+func (a *Activities) CapturePayment(ctx context.Context, input app.OrderInput, payment app.Payment) (string, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Capture Payment activity started", "orderId", input.OrderId, "authorizationId", payment.AuthorizationId)
+	if err := scenarioFailure(ctx, input, "CapturePayment"); err != nil {
+		return "", err
+	}
+
+	err := a.Payments.Capture(ctx, payment.AuthorizationId, payment.Amount)
+	if err != nil {
+		return "", paymentError("capture payment activity failed", err)
 	}
 
 	return input.OrderId, nil
 }
 
-func UndoChargeCustomer(ctx context.Context, input app.OrderInput) (string, error) {
+// UndoChargeCustomer voids an authorization that was never captured and
+// refunds a captured payment.
+func (a *Activities) UndoChargeCustomer(ctx context.Context, input app.OrderInput, payment app.Payment) (string, error) {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Undo Charge Customer activity started", "orderId", input.OrderId)
+	logger.Info("Undo Charge Customer activity started", "orderId", input.OrderId, "authorizationId", payment.AuthorizationId)
 
-	// simulate external API call
-	simulateExternalOperation(1000)
+	var err error
+	switch {
+	case payment.AuthorizationId == "":
+		logger.Info("No payment to undo", "orderId", input.OrderId)
+	case payment.Captured:
+		logger.Info("Refunding payment", "orderId", input.OrderId, "amount", payment.Amount)
+		err = a.Payments.Refund(ctx, payment.AuthorizationId, undoChargeRefundId(input), payment.Amount)
+	default:
+		logger.Info("Voiding authorization", "orderId", input.OrderId)
+		err = a.Payments.Void(ctx, payment.AuthorizationId)
+	}
+	if err != nil {
+		return "", paymentError("undo charge customer activity failed", err)
+	}
 
 	return input.OrderId, nil
 }
+
+// undoChargeRefundId keys the refund of a rolled back order, so a retried
+// UndoChargeCustomer refunds it once.
+func undoChargeRefundId(input app.OrderInput) string {
+	return "undo-charge-" + input.OrderId
+}
+
+// recordChargeFailure counts failed charge attempts by the type of failure.
+func recordChargeFailure(ctx context.Context, failureType string) {
+	activity.GetMetricsHandler(ctx).WithTags(map[string]string{"type": failureType}).Counter(app.MetricChargeFailures).Inc(1)
+}
+
+// paymentError makes gateway errors that retrying cannot fix non-retryable.
+func paymentError(msg string, err error) error {
+	if errors.Is(err, ErrPaymentDeclined) || errors.Is(err, ErrUnknownAuthorization) || errors.Is(err, ErrInvalidPaymentState) {
+		return temporal.NewNonRetryableApplicationError(msg, "paymentFailure", err)
+	}
+	return err
+}
@@ -13,6 +13,9 @@ func CheckFraud(ctx context.Context, input app.OrderInput) (string, error) {
 
 	// simulate external API call
 	simulateExternalOperation(1000)
+	if err := scenarioFailure(ctx, input, "CheckFraud"); err != nil {
+		return "", err
+	}
 
 	return input.OrderId, nil
 }
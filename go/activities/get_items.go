@@ -2,23 +2,69 @@ package activities
 
 import (
 	"context"
+	"fmt"
 	"sort"
 	"temporal-order-management/app"
 
 	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
 )
 
-func GetItems(ctx context.Context) (app.Items, error) {
+// defaultLineItems is used for orders placed without any line items, e.g. by
+// the UI, which only sends an order id and an address.
+var defaultLineItems = []app.LineItem{
+	{Sku: "TBL-TOP", Quantity: 1},
+	{Sku: "TBL-LEGS", Quantity: 2},
+	{Sku: "KEYPAD", Quantity: 1},
+}
+
+func GetItems(ctx context.Context, input app.OrderInput) (app.Items, error) {
 	logger := activity.GetLogger(ctx)
-	logger.Info("Getting list of items")
+	logger.Info("Getting list of items", "orderId", input.OrderId)
 
 	// simulate DB query
 	simulateExternalOperation(100)
 
-	itemList := app.Items{
-		{Id: 654300, Description: "Table Top", Quantity: 1},
-		{Id: 654321, Description: "Table Legs", Quantity: 2},
-		{Id: 654322, Description: "Keypad", Quantity: 1},
+	lines := input.Items
+	if len(lines) == 0 {
+		lines = defaultLineItems
+	}
+
+	itemList := app.Items{}
+	lineOf := map[string]int{}
+	for _, line := range lines {
+		if line.Quantity <= 0 {
+			return nil, temporal.NewNonRetryableApplicationError(
+				fmt.Sprintf("invalid quantity %v for sku %v", line.Quantity, line.Sku), "invalidOrder", nil)
+		}
+
+		entry, ok, err := catalog.Lookup(ctx, line.Sku)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, temporal.NewNonRetryableApplicationError(
+				fmt.Sprintf("unknown sku %v", line.Sku), "invalidOrder", nil)
+		}
+		if line.UnitPrice != 0 && line.UnitPrice != entry.UnitPrice {
+			return nil, temporal.NewNonRetryableApplicationError(
+				fmt.Sprintf("price mismatch for sku %v: ordered at %v, catalog price %v", line.Sku, line.UnitPrice, entry.UnitPrice), "invalidOrder", nil)
+		}
+
+		// the item id identifies the item in shipments and returns, so lines
+		// of the same sku become one item
+		if i, ok := lineOf[entry.Sku]; ok {
+			itemList[i].Quantity += line.Quantity
+			continue
+		}
+		lineOf[entry.Sku] = len(itemList)
+		itemList = append(itemList, app.Item{
+			Id:          entry.Id,
+			Sku:         entry.Sku,
+			Description: entry.Description,
+			Quantity:    line.Quantity,
+			UnitPrice:   entry.UnitPrice,
+		})
 	}
 	sort.Sort(itemList)
 
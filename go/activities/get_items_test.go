@@ -0,0 +1,46 @@
+package activities
+
+import (
+	"errors"
+	"temporal-order-management/app"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func getItems(t *testing.T, lines []app.LineItem) (app.Items, error) {
+	var ts testsuite.WorkflowTestSuite
+	env := ts.NewTestActivityEnvironment()
+	env.RegisterActivity(GetItems)
+	value, err := env.ExecuteActivity(GetItems, app.OrderInput{OrderId: "1", Items: lines})
+	if err != nil {
+		return nil, err
+	}
+	var items app.Items
+	require.NoError(t, value.Get(&items))
+	return items, nil
+}
+
+func TestGetItemsMergesLinesOfTheSameSku(t *testing.T) {
+	items, err := getItems(t, []app.LineItem{
+		{Sku: "TBL-LEGS", Quantity: 2},
+		{Sku: "KEYPAD", Quantity: 1},
+		{Sku: "TBL-LEGS", Quantity: 2},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	require.Equal(t, "TBL-LEGS", items[0].Sku)
+	require.Equal(t, 4, items[0].Quantity)
+	require.Equal(t, int64(4*2450+3999), items.Total())
+}
+
+func TestGetItemsRejectsUnknownSku(t *testing.T) {
+	_, err := getItems(t, []app.LineItem{{Sku: "NOPE", Quantity: 1}})
+
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, "invalidOrder", appErr.Type())
+}
@@ -0,0 +1,198 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"temporal-order-management/app"
+)
+
+// OutOfStockErrorType is the application error type returned by
+// ReserveInventory when an item is unavailable. The error details hold an
+// OutOfStockError naming the SKU.
+const OutOfStockErrorType = "OutOfStock"
+
+type OutOfStockError struct {
+	Sku       string `json:"sku"`
+	Requested int    `json:"requested"`
+	Available int    `json:"available"`
+}
+
+func (e *OutOfStockError) Error() string {
+	return fmt.Sprintf("sku %v is out of stock: requested %v, available %v", e.Sku, e.Requested, e.Available)
+}
+
+// InventoryService reserves stock for an order. Reservations are keyed by order
+// id, so reserving twice for the same order is a no-op and releasing an order
+// without a reservation succeeds. Returned items are restocked the same way,
+// keyed by return id.
+type InventoryService interface {
+	Reserve(ctx context.Context, orderId string, items app.Items) error
+	Release(ctx context.Context, orderId string) error
+	Restock(ctx context.Context, returnId string, items app.Items) error
+	UndoRestock(ctx context.Context, returnId string) error
+}
+
+// DefaultStock is the starting stock for the items in DefaultCatalog.
+var DefaultStock = map[string]int{
+	"TBL-TOP":  100,
+	"TBL-LEGS": 200,
+	"KEYPAD":   100,
+}
+
+type inventoryState struct {
+	Stock        map[string]int            `json:"stock"`
+	Reservations map[string]map[string]int `json:"reservations"`
+	Restocks     map[string]map[string]int `json:"restocks"`
+}
+
+func newInventoryState(stock map[string]int) *inventoryState {
+	s := &inventoryState{
+		Stock:        map[string]int{},
+		Reservations: map[string]map[string]int{},
+		Restocks:     map[string]map[string]int{},
+	}
+	for sku, quantity := range stock {
+		s.Stock[sku] = quantity
+	}
+	return s
+}
+
+// reserve takes stock for every item or for none of them.
+func (s *inventoryState) reserve(orderId string, items app.Items) error {
+	if _, ok := s.Reservations[orderId]; ok {
+		return nil
+	}
+
+	wanted := map[string]int{}
+	for _, item := range items {
+		wanted[item.Sku] += item.Quantity
+	}
+	for _, item := range items {
+		if available := s.Stock[item.Sku]; available < wanted[item.Sku] {
+			return &OutOfStockError{Sku: item.Sku, Requested: wanted[item.Sku], Available: available}
+		}
+	}
+
+	for sku, quantity := range wanted {
+		s.Stock[sku] -= quantity
+	}
+	s.Reservations[orderId] = wanted
+	return nil
+}
+
+func (s *inventoryState) release(orderId string) {
+	for sku, quantity := range s.Reservations[orderId] {
+		s.Stock[sku] += quantity
+	}
+	delete(s.Reservations, orderId)
+}
+
+// restock puts returned items back in stock.
+func (s *inventoryState) restock(returnId string, items app.Items) {
+	if _, ok := s.Restocks[returnId]; ok {
+		return
+	}
+	restocked := map[string]int{}
+	for _, item := range items {
+		restocked[item.Sku] += item.Quantity
+		s.Stock[item.Sku] += item.Quantity
+	}
+	s.Restocks[returnId] = restocked
+}
+
+func (s *inventoryState) undoRestock(returnId string) {
+	for sku, quantity := range s.Restocks[returnId] {
+		s.Stock[sku] -= quantity
+	}
+	delete(s.Restocks, returnId)
+}
+
+// MemoryInventory is an InventoryService that keeps stock in memory.
+type MemoryInventory struct {
+	mu    sync.Mutex
+	state *inventoryState
+}
+
+func NewMemoryInventory(stock map[string]int) *MemoryInventory {
+	return &MemoryInventory{state: newInventoryState(stock)}
+}
+
+func (m *MemoryInventory) Reserve(ctx context.Context, orderId string, items app.Items) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.reserve(orderId, items)
+}
+
+func (m *MemoryInventory) Release(ctx context.Context, orderId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.release(orderId)
+	return nil
+}
+
+func (m *MemoryInventory) Restock(ctx context.Context, returnId string, items app.Items) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.restock(returnId, items)
+	return nil
+}
+
+func (m *MemoryInventory) UndoRestock(ctx context.Context, returnId string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.undoRestock(returnId)
+	return nil
+}
+
+// FileInventory is an InventoryService that keeps stock and reservations in a
+// JSON file, so they survive worker restarts and are shared by the workers
+// using the same file.
+type FileInventory struct {
+	file jsonFile
+}
+
+// NewFileInventory uses the inventory at path, creating it with the given stock
+// if it doesn't exist yet.
+func NewFileInventory(path string, stock map[string]int) (*FileInventory, error) {
+	f := &FileInventory{file: jsonFile{path: path}}
+	err := f.file.create(newInventoryState(stock))
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileInventory) Reserve(ctx context.Context, orderId string, items app.Items) error {
+	return f.update(func(s *inventoryState) error {
+		return s.reserve(orderId, items)
+	})
+}
+
+func (f *FileInventory) Release(ctx context.Context, orderId string) error {
+	return f.update(func(s *inventoryState) error {
+		s.release(orderId)
+		return nil
+	})
+}
+
+func (f *FileInventory) Restock(ctx context.Context, returnId string, items app.Items) error {
+	return f.update(func(s *inventoryState) error {
+		s.restock(returnId, items)
+		return nil
+	})
+}
+
+func (f *FileInventory) UndoRestock(ctx context.Context, returnId string) error {
+	return f.update(func(s *inventoryState) error {
+		s.undoRestock(returnId)
+		return nil
+	})
+}
+
+func (f *FileInventory) update(fn func(*inventoryState) error) error {
+	state := newInventoryState(nil)
+	return f.file.update(state, func() error {
+		return fn(state)
+	})
+}
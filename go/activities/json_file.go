@@ -0,0 +1,95 @@
+package activities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// jsonFile keeps the state of a file-backed service in a JSON file. Updates
+// hold a lock on the file, so workers in several processes can share it, and
+// replace it atomically, so a crash can't leave a truncated file behind.
+type jsonFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+// create writes state to the file unless it already exists.
+func (f *jsonFile) create(state any) error {
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, err = os.Stat(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f.save(state)
+	}
+	return err
+}
+
+// update reads the file into state and writes state back once fn succeeds.
+func (f *jsonFile) update(state any, fn func() error) error {
+	unlock, err := f.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return fmt.Errorf("failed to parse %v: %w", f.path, err)
+	}
+
+	err = fn()
+	if err != nil {
+		return err
+	}
+	return f.save(state)
+}
+
+// lock serializes updates within the process and, through a lock file next to
+// the state, between processes.
+func (f *jsonFile) lock() (func(), error) {
+	f.mu.Lock()
+	unlock, err := lockFile(f.path + ".lock")
+	if err != nil {
+		f.mu.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		f.mu.Unlock()
+	}, nil
+}
+
+// save writes the state to a temporary file first and renames it over the
+// previous state.
+func (f *jsonFile) save(state any) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
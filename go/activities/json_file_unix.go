@@ -0,0 +1,26 @@
+//go:build unix
+
+package activities
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on path, which the OS releases if the
+// process dies while holding it.
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
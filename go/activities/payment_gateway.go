@@ -0,0 +1,234 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrPaymentDeclined      = errors.New("payment declined")
+	ErrUnknownAuthorization = errors.New("unknown authorization")
+	ErrInvalidPaymentState  = errors.New("invalid payment state")
+)
+
+// DeclinedPaymentRef is declined by MemoryPaymentGateway and the payment stub.
+const DeclinedPaymentRef = "declined"
+
+// PaymentGateway authorizes a payment up front and captures it once the order
+// has shipped. An authorization that was never captured is voided, a captured
+// payment is refunded. Refunds are keyed by refundId, so repeating a refund
+// with the same id is a no-op. Amounts are in cents.
+type PaymentGateway interface {
+	Authorize(ctx context.Context, orderId string, paymentRef string, amount int64) (string, error)
+	Capture(ctx context.Context, authorizationId string, amount int64) error
+	Void(ctx context.Context, authorizationId string) error
+	Refund(ctx context.Context, authorizationId string, refundId string, amount int64) error
+}
+
+type paymentState string
+
+const (
+	paymentAuthorized paymentState = "authorized"
+	paymentCaptured   paymentState = "captured"
+	paymentVoided     paymentState = "voided"
+)
+
+type payment struct {
+	OrderId  string           `json:"orderId"`
+	Amount   int64            `json:"amount"`
+	Captured int64            `json:"captured"`
+	Refunds  map[string]int64 `json:"refunds,omitempty"`
+	State    paymentState     `json:"state"`
+}
+
+func (p *payment) refunded() int64 {
+	var refunded int64
+	for _, amount := range p.Refunds {
+		refunded += amount
+	}
+	return refunded
+}
+
+// paymentsState holds the payments of a gateway by authorization id, and the
+// authorization of each order. Authorizations are idempotent per order id so
+// that retried activities don't authorize twice.
+type paymentsState struct {
+	Payments map[string]*payment `json:"payments"`
+	Orders   map[string]string   `json:"orders"`
+}
+
+func newPaymentsState() *paymentsState {
+	return &paymentsState{
+		Payments: map[string]*payment{},
+		Orders:   map[string]string{},
+	}
+}
+
+func (s *paymentsState) authorize(orderId string, paymentRef string, amount int64) (string, error) {
+	if paymentRef == DeclinedPaymentRef {
+		return "", ErrPaymentDeclined
+	}
+	if authorizationId, ok := s.Orders[orderId]; ok {
+		return authorizationId, nil
+	}
+
+	authorizationId := "auth-" + uuid.New().String()
+	s.Payments[authorizationId] = &payment{OrderId: orderId, Amount: amount, State: paymentAuthorized}
+	s.Orders[orderId] = authorizationId
+	return authorizationId, nil
+}
+
+func (s *paymentsState) capture(authorizationId string, amount int64) error {
+	p, err := s.lookup(authorizationId)
+	if err != nil {
+		return err
+	}
+	switch {
+	case p.State == paymentCaptured:
+		return nil
+	case p.State != paymentAuthorized:
+		return fmt.Errorf("%w: cannot capture %v payment", ErrInvalidPaymentState, p.State)
+	case amount > p.Amount:
+		return fmt.Errorf("%w: capture of %v exceeds authorized %v", ErrInvalidPaymentState, amount, p.Amount)
+	}
+	p.Captured = amount
+	p.State = paymentCaptured
+	return nil
+}
+
+func (s *paymentsState) void(authorizationId string) error {
+	p, err := s.lookup(authorizationId)
+	if err != nil {
+		return err
+	}
+	switch p.State {
+	case paymentVoided:
+		return nil
+	case paymentCaptured:
+		return fmt.Errorf("%w: cannot void captured payment", ErrInvalidPaymentState)
+	}
+	p.State = paymentVoided
+	return nil
+}
+
+func (s *paymentsState) refund(authorizationId string, refundId string, amount int64) error {
+	p, err := s.lookup(authorizationId)
+	if err != nil {
+		return err
+	}
+	if _, ok := p.Refunds[refundId]; ok {
+		return nil
+	}
+	if p.State != paymentCaptured {
+		return fmt.Errorf("%w: cannot refund %v payment", ErrInvalidPaymentState, p.State)
+	}
+	if remaining := p.Captured - p.refunded(); amount > remaining {
+		return fmt.Errorf("%w: refund of %v exceeds remaining %v", ErrInvalidPaymentState, amount, remaining)
+	}
+	if p.Refunds == nil {
+		p.Refunds = map[string]int64{}
+	}
+	p.Refunds[refundId] = amount
+	return nil
+}
+
+func (s *paymentsState) lookup(authorizationId string) (*payment, error) {
+	p, ok := s.Payments[authorizationId]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownAuthorization, authorizationId)
+	}
+	return p, nil
+}
+
+// MemoryPaymentGateway is an in-memory PaymentGateway. Its payments are lost
+// when the process exits, so it only suits a single worker process that is not
+// restarted.
+type MemoryPaymentGateway struct {
+	mu    sync.Mutex
+	state *paymentsState
+}
+
+func NewMemoryPaymentGateway() *MemoryPaymentGateway {
+	return &MemoryPaymentGateway{state: newPaymentsState()}
+}
+
+func (g *MemoryPaymentGateway) Authorize(ctx context.Context, orderId string, paymentRef string, amount int64) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state.authorize(orderId, paymentRef, amount)
+}
+
+func (g *MemoryPaymentGateway) Capture(ctx context.Context, authorizationId string, amount int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state.capture(authorizationId, amount)
+}
+
+func (g *MemoryPaymentGateway) Void(ctx context.Context, authorizationId string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state.void(authorizationId)
+}
+
+func (g *MemoryPaymentGateway) Refund(ctx context.Context, authorizationId string, refundId string, amount int64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.state.refund(authorizationId, refundId, amount)
+}
+
+// FilePaymentGateway is a PaymentGateway that keeps payments in a JSON file, so
+// an authorization made by one worker can be captured, voided or refunded by
+// another, or by the same worker after a restart.
+type FilePaymentGateway struct {
+	file jsonFile
+}
+
+// NewFilePaymentGateway uses the payments at path, creating the file if it
+// doesn't exist yet.
+func NewFilePaymentGateway(path string) (*FilePaymentGateway, error) {
+	g := &FilePaymentGateway{file: jsonFile{path: path}}
+	err := g.file.create(newPaymentsState())
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *FilePaymentGateway) Authorize(ctx context.Context, orderId string, paymentRef string, amount int64) (string, error) {
+	var authorizationId string
+	err := g.update(func(s *paymentsState) error {
+		var err error
+		authorizationId, err = s.authorize(orderId, paymentRef, amount)
+		return err
+	})
+	return authorizationId, err
+}
+
+func (g *FilePaymentGateway) Capture(ctx context.Context, authorizationId string, amount int64) error {
+	return g.update(func(s *paymentsState) error {
+		return s.capture(authorizationId, amount)
+	})
+}
+
+func (g *FilePaymentGateway) Void(ctx context.Context, authorizationId string) error {
+	return g.update(func(s *paymentsState) error {
+		return s.void(authorizationId)
+	})
+}
+
+func (g *FilePaymentGateway) Refund(ctx context.Context, authorizationId string, refundId string, amount int64) error {
+	return g.update(func(s *paymentsState) error {
+		return s.refund(authorizationId, refundId, amount)
+	})
+}
+
+func (g *FilePaymentGateway) update(fn func(*paymentsState) error) error {
+	state := newPaymentsState()
+	return g.file.update(state, func() error {
+		return fn(state)
+	})
+}
@@ -0,0 +1,175 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPPaymentGateway is a PaymentGateway talking JSON over HTTP, e.g. to the
+// stub served by NewPaymentStubHandler.
+type HTTPPaymentGateway struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+func NewHTTPPaymentGateway(baseURL string) *HTTPPaymentGateway {
+	return &HTTPPaymentGateway{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type authorizeRequest struct {
+	OrderId    string `json:"orderId"`
+	PaymentRef string `json:"paymentRef"`
+	Amount     int64  `json:"amount"`
+}
+
+type authorizeResponse struct {
+	AuthorizationId string `json:"authorizationId"`
+}
+
+type amountRequest struct {
+	Amount int64 `json:"amount"`
+}
+
+func (g *HTTPPaymentGateway) Authorize(ctx context.Context, orderId string, paymentRef string, amount int64) (string, error) {
+	var resp authorizeResponse
+	err := g.post(ctx, "/authorizations", orderId, authorizeRequest{OrderId: orderId, PaymentRef: paymentRef, Amount: amount}, &resp)
+	if err != nil {
+		return "", err
+	}
+	return resp.AuthorizationId, nil
+}
+
+func (g *HTTPPaymentGateway) Capture(ctx context.Context, authorizationId string, amount int64) error {
+	return g.post(ctx, "/authorizations/"+url.PathEscape(authorizationId)+"/capture", "", amountRequest{Amount: amount}, nil)
+}
+
+func (g *HTTPPaymentGateway) Void(ctx context.Context, authorizationId string) error {
+	return g.post(ctx, "/authorizations/"+url.PathEscape(authorizationId)+"/void", "", nil, nil)
+}
+
+func (g *HTTPPaymentGateway) Refund(ctx context.Context, authorizationId string, refundId string, amount int64) error {
+	return g.post(ctx, "/authorizations/"+url.PathEscape(authorizationId)+"/refund", refundId, amountRequest{Amount: amount}, nil)
+}
+
+func (g *HTTPPaymentGateway) post(ctx context.Context, path string, idempotencyKey string, body any, out any) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&reqBody).Encode(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.BaseURL+path, &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := g.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(resp.Body)
+		detail := strings.TrimSpace(string(msg))
+		switch resp.StatusCode {
+		case http.StatusPaymentRequired:
+			return fmt.Errorf("%w: %v", ErrPaymentDeclined, detail)
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %v", ErrUnknownAuthorization, detail)
+		case http.StatusConflict:
+			return fmt.Errorf("%w: %v", ErrInvalidPaymentState, detail)
+		default:
+			return fmt.Errorf("payment gateway returned %v: %v", resp.Status, detail)
+		}
+	}
+
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// NewPaymentStubHandler serves the HTTPPaymentGateway protocol on top of another
+// gateway, typically a MemoryPaymentGateway.
+func NewPaymentStubHandler(gateway PaymentGateway) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /authorizations", func(w http.ResponseWriter, r *http.Request) {
+		var req authorizeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		authorizationId, err := gateway.Authorize(r.Context(), req.OrderId, req.PaymentRef, req.Amount)
+		if err != nil {
+			writePaymentError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(authorizeResponse{AuthorizationId: authorizationId})
+	})
+
+	mux.HandleFunc("POST /authorizations/{id}/capture", func(w http.ResponseWriter, r *http.Request) {
+		var req amountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writePaymentError(w, gateway.Capture(r.Context(), r.PathValue("id"), req.Amount))
+	})
+
+	mux.HandleFunc("POST /authorizations/{id}/void", func(w http.ResponseWriter, r *http.Request) {
+		writePaymentError(w, gateway.Void(r.Context(), r.PathValue("id")))
+	})
+
+	// refunds are keyed by the Idempotency-Key header
+	mux.HandleFunc("POST /authorizations/{id}/refund", func(w http.ResponseWriter, r *http.Request) {
+		refundId := r.Header.Get("Idempotency-Key")
+		if refundId == "" {
+			http.Error(w, "Idempotency-Key header is required", http.StatusBadRequest)
+			return
+		}
+		var req amountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writePaymentError(w, gateway.Refund(r.Context(), r.PathValue("id"), refundId, req.Amount))
+	})
+
+	return mux
+}
+
+func writePaymentError(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusOK)
+	case errors.Is(err, ErrPaymentDeclined):
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+	case errors.Is(err, ErrUnknownAuthorization):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrInvalidPaymentState):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
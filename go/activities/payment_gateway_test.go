@@ -0,0 +1,56 @@
+package activities
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePaymentGatewayIsSharedBetweenWorkers(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "payments.json")
+	v1, err := NewFilePaymentGateway(path)
+	require.NoError(t, err)
+	authorizationId, err := v1.Authorize(ctx, "123456", "tok_visa", 1000)
+	require.NoError(t, err)
+
+	// a second worker, or the first one after a restart, sees the authorization
+	v2, err := NewFilePaymentGateway(path)
+	require.NoError(t, err)
+	again, err := v2.Authorize(ctx, "123456", "tok_visa", 1000)
+	require.NoError(t, err)
+	require.Equal(t, authorizationId, again)
+	require.NoError(t, v2.Capture(ctx, authorizationId, 1000))
+	require.NoError(t, v1.Refund(ctx, authorizationId, "return-1", 400))
+	require.ErrorIs(t, v2.Void(ctx, authorizationId), ErrInvalidPaymentState)
+	require.ErrorIs(t, v2.Capture(ctx, "auth-unknown", 1000), ErrUnknownAuthorization)
+}
+
+func TestPaymentGatewayRefundsAreIdempotent(t *testing.T) {
+	file, err := NewFilePaymentGateway(filepath.Join(t.TempDir(), "payments.json"))
+	require.NoError(t, err)
+	server := httptest.NewServer(NewPaymentStubHandler(NewMemoryPaymentGateway()))
+	defer server.Close()
+
+	for name, gateway := range map[string]PaymentGateway{
+		"memory": NewMemoryPaymentGateway(),
+		"file":   file,
+		"http":   NewHTTPPaymentGateway(server.URL),
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			authorizationId, err := gateway.Authorize(ctx, "123456", "tok_visa", 1000)
+			require.NoError(t, err)
+			require.NoError(t, gateway.Capture(ctx, authorizationId, 1000))
+
+			require.NoError(t, gateway.Refund(ctx, authorizationId, "return-1", 600))
+			// a retry of the same refund is not refunded again
+			require.NoError(t, gateway.Refund(ctx, authorizationId, "return-1", 600))
+			require.NoError(t, gateway.Refund(ctx, authorizationId, "return-2", 400))
+			require.ErrorIs(t, gateway.Refund(ctx, authorizationId, "return-3", 1), ErrInvalidPaymentState)
+		})
+	}
+}
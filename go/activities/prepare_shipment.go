@@ -13,6 +13,9 @@ func PrepareShipment(ctx context.Context, input app.OrderInput) (string, error)
 
 	// simulate external API call
 	simulateExternalOperation(1000)
+	if err := scenarioFailure(ctx, input, "PrepareShipment"); err != nil {
+		return "", err
+	}
 
 	return input.OrderId, nil
 }
@@ -0,0 +1,43 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"temporal-order-management/app"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+func (a *Activities) ReserveInventory(ctx context.Context, input app.OrderInput, items app.Items) (string, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Reserve Inventory activity started", "orderId", input.OrderId)
+	if err := scenarioFailure(ctx, input, "ReserveInventory"); err != nil {
+		return "", err
+	}
+
+	err := a.Inventory.Reserve(ctx, input.OrderId, items)
+	var outOfStock *OutOfStockError
+	if errors.As(err, &outOfStock) {
+		// a business error, which cannot be retried
+		logger.Info("Item out of stock", "orderId", input.OrderId, "sku", outOfStock.Sku)
+		return "", temporal.NewNonRetryableApplicationError(outOfStock.Error(), OutOfStockErrorType, nil, *outOfStock)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return input.OrderId, nil
+}
+
+func (a *Activities) ReleaseInventory(ctx context.Context, input app.OrderInput) (string, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Release Inventory activity started", "orderId", input.OrderId)
+
+	err := a.Inventory.Release(ctx, input.OrderId)
+	if err != nil {
+		return "", err
+	}
+
+	return input.OrderId, nil
+}
@@ -0,0 +1,88 @@
+package activities
+
+import (
+	"context"
+	"fmt"
+	"temporal-order-management/app"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// InvalidConditionErrorType is the application error type returned by
+// InspectReturn for an unknown item condition.
+const InvalidConditionErrorType = "InvalidCondition"
+
+// OpenedItemRefundPercent is refunded for items that were opened, the rest
+// is kept as a restocking fee.
+const OpenedItemRefundPercent = 80
+
+// InspectReturn decides, from the condition the item arrived in, whether it
+// can be restocked and how much of its price is refunded.
+func InspectReturn(ctx context.Context, input app.ReturnInput, condition string) (app.Inspection, error) {
+	logger := activity.GetLogger(ctx)
+	item := input.Shipment.Item
+	logger.Info("Inspect Return activity started", "orderId", input.Shipment.Order.OrderId, "itemId", item.Id, "condition", condition)
+
+	// simulate external API call
+	simulateExternalOperation(500)
+
+	price := item.UnitPrice * int64(item.Quantity)
+	inspection := app.Inspection{Condition: condition}
+	switch condition {
+	case app.ReturnConditionUnopened:
+		inspection.Restock = true
+		inspection.RefundAmount = price
+	case app.ReturnConditionOpened:
+		inspection.Restock = true
+		inspection.RefundAmount = price * OpenedItemRefundPercent / 100
+	case app.ReturnConditionDamaged:
+		// neither restocked nor refunded
+	default:
+		return app.Inspection{}, temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("unknown condition %q", condition), InvalidConditionErrorType, nil)
+	}
+	// never refund more than is left of the payment
+	inspection.RefundAmount = min(inspection.RefundAmount, input.Payment.Amount)
+	return inspection, nil
+}
+
+// RestockItem puts the returned item back in stock.
+func (a *Activities) RestockItem(ctx context.Context, input app.ReturnInput) (string, error) {
+	returnId := returnId(input)
+	activity.GetLogger(ctx).Info("Restock Item activity started", "returnId", returnId)
+
+	err := a.Inventory.Restock(ctx, returnId, app.Items{input.Shipment.Item})
+	if err != nil {
+		return "", err
+	}
+	return returnId, nil
+}
+
+// UndoRestockItem takes a restocked item out of stock again.
+func (a *Activities) UndoRestockItem(ctx context.Context, input app.ReturnInput) (string, error) {
+	returnId := returnId(input)
+	activity.GetLogger(ctx).Info("Undo Restock Item activity started", "returnId", returnId)
+
+	err := a.Inventory.UndoRestock(ctx, returnId)
+	if err != nil {
+		return "", err
+	}
+	return returnId, nil
+}
+
+// RefundCustomer refunds amount of the order's captured payment.
+func (a *Activities) RefundCustomer(ctx context.Context, input app.ReturnInput, amount int64) (string, error) {
+	logger := activity.GetLogger(ctx)
+	logger.Info("Refund Customer activity started", "orderId", input.Shipment.Order.OrderId, "authorizationId", input.Payment.AuthorizationId, "amount", amount)
+
+	err := a.Payments.Refund(ctx, input.Payment.AuthorizationId, returnId(input), amount)
+	if err != nil {
+		return "", paymentError("refund customer activity failed", err)
+	}
+	return input.Shipment.Order.OrderId, nil
+}
+
+func returnId(input app.ReturnInput) string {
+	return app.ReturnWorkflowId(input.Shipment.Order.OrderId, input.Shipment.Item.Id)
+}
@@ -0,0 +1,113 @@
+package activities
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"temporal-order-management/app"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// NoWarehouseErrorType is the application error type returned by
+// RouteShipments when no warehouse stocks an item.
+const NoWarehouseErrorType = "NoWarehouse"
+
+// Warehouse ships orders from the stock it holds.
+type Warehouse struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// Stock is the quantity held by SKU, as known to routing
+	Stock map[string]int `json:"stock"`
+}
+
+// Warehouses is the routing table, in order of preference.
+type Warehouses []Warehouse
+
+// DefaultWarehouses splits the default order across two warehouses.
+var DefaultWarehouses = Warehouses{
+	{Id: "east", Name: "Newark, NJ", Stock: map[string]int{"TBL-TOP": 50, "KEYPAD": 100}},
+	{Id: "west", Name: "Reno, NV", Stock: map[string]int{"TBL-TOP": 20, "TBL-LEGS": 200}},
+}
+
+// LoadWarehousesFile reads a JSON array of warehouses.
+func LoadWarehousesFile(path string) (Warehouses, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var warehouses Warehouses
+	err = json.Unmarshal(data, &warehouses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse warehouses %v: %w", path, err)
+	}
+	return warehouses, nil
+}
+
+// Route assigns each item to a warehouse holding enough stock, preferring a
+// warehouse the order already ships from, and groups the items into one
+// shipment per warehouse. Items no warehouse can ship fail with a
+// NoWarehouseErrorType error.
+func (w Warehouses) Route(items app.Items) ([]app.Shipment, error) {
+	// stock left after the items routed so far
+	stock := make([]map[string]int, len(w))
+	for i, warehouse := range w {
+		stock[i] = map[string]int{}
+		for sku, quantity := range warehouse.Stock {
+			stock[i][sku] = quantity
+		}
+	}
+
+	sorted := append(app.Items{}, items...)
+	sort.Sort(sorted)
+	var shipments []app.Shipment
+	shipmentOf := map[int]int{}
+	for _, item := range sorted {
+		chosen := -1
+		for i := range w {
+			if stock[i][item.Sku] < item.Quantity {
+				continue
+			}
+			if _, ok := shipmentOf[i]; ok {
+				chosen = i
+				break
+			}
+			if chosen < 0 {
+				chosen = i
+			}
+		}
+		if chosen < 0 {
+			return nil, temporal.NewNonRetryableApplicationError(
+				fmt.Sprintf("no warehouse can ship %v of sku %v", item.Quantity, item.Sku), NoWarehouseErrorType, nil)
+		}
+
+		stock[chosen][item.Sku] -= item.Quantity
+		s, ok := shipmentOf[chosen]
+		if !ok {
+			s = len(shipments)
+			shipmentOf[chosen] = s
+			shipments = append(shipments, app.Shipment{Id: w[chosen].Id, Warehouse: w[chosen].Name})
+		}
+		shipments[s].Items = append(shipments[s].Items, item)
+	}
+	return shipments, nil
+}
+
+// RouteShipments splits the order into shipments, one per warehouse it ships
+// from.
+func (a *Activities) RouteShipments(ctx context.Context, input app.OrderInput, items app.Items) ([]app.Shipment, error) {
+	warehouses := a.Warehouses
+	if warehouses == nil {
+		warehouses = DefaultWarehouses
+	}
+	shipments, err := warehouses.Route(items)
+	if err != nil {
+		return nil, err
+	}
+	activity.GetLogger(ctx).Info("Routed order", "orderId", input.OrderId, "shipments", len(shipments))
+	return shipments, nil
+}
@@ -0,0 +1,64 @@
+package activities
+
+import (
+	"errors"
+	"temporal-order-management/app"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+)
+
+func TestRouteSplitsOrderAcrossWarehouses(t *testing.T) {
+	items := app.Items{
+		{Id: 654321, Sku: "TBL-LEGS", Quantity: 2},
+		{Id: 654300, Sku: "TBL-TOP", Quantity: 1},
+	}
+
+	shipments, err := DefaultWarehouses.Route(items)
+
+	require.NoError(t, err)
+	require.Len(t, shipments, 2)
+	require.Equal(t, "east", shipments[0].Id)
+	require.Equal(t, app.Items{items[1]}, shipments[0].Items)
+	require.Equal(t, "west", shipments[1].Id)
+	require.Equal(t, app.Items{items[0]}, shipments[1].Items)
+}
+
+func TestRoutePrefersWarehouseAlreadyShipping(t *testing.T) {
+	items := app.Items{
+		{Id: 1, Sku: "TBL-LEGS", Quantity: 2},
+		{Id: 2, Sku: "TBL-TOP", Quantity: 1},
+	}
+
+	shipments, err := DefaultWarehouses.Route(items)
+
+	// the legs only ship from west, so the top ships with them
+	require.NoError(t, err)
+	require.Len(t, shipments, 1)
+	require.Equal(t, "west", shipments[0].Id)
+	require.Equal(t, items, shipments[0].Items)
+}
+
+func TestRouteCountsStockAcrossItems(t *testing.T) {
+	warehouses := Warehouses{
+		{Id: "a", Stock: map[string]int{"TBL-TOP": 1}},
+		{Id: "b", Stock: map[string]int{"TBL-TOP": 1}},
+	}
+	items := app.Items{{Id: 1, Sku: "TBL-TOP", Quantity: 1}, {Id: 2, Sku: "TBL-TOP", Quantity: 1}}
+
+	shipments, err := warehouses.Route(items)
+
+	require.NoError(t, err)
+	require.Len(t, shipments, 2)
+	require.Equal(t, 1, warehouses[0].Stock["TBL-TOP"])
+}
+
+func TestRouteFailsWithoutStock(t *testing.T) {
+	_, err := DefaultWarehouses.Route(app.Items{{Id: 1, Sku: "TBL-TOP", Quantity: 100}})
+
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(err, &appErr))
+	require.Equal(t, NoWarehouseErrorType, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+}
@@ -1,18 +1,35 @@
 package activities
 
-import "time"
+import (
+	"context"
+	"temporal-order-management/app"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
 
 func simulateExternalOperation(ms int) {
 	time.Sleep(time.Duration(ms) * time.Millisecond)
 }
 
-func simulateExternalOperationWithError(ms int, name string, attempt int32) string {
-	simulateExternalOperation(ms / int(attempt))
-	var result string
-	if attempt < 5 {
-		result = name
-	} else {
-		result = "NoError"
+// scenarioFailure returns the error the order's scenario injects into step on
+// the current attempt, or nil.
+func scenarioFailure(ctx context.Context, input app.OrderInput, step string) error {
+	failure := input.Scenario.FailsStep(step)
+	if failure == nil {
+		return nil
+	}
+	attempt := activity.GetInfo(ctx).Attempt
+	if !failure.FailsAttempt(attempt) {
+		return nil
+	}
+
+	activity.GetLogger(ctx).Info("Scenario failure", "step", step, "attempt", attempt, "type", failure.Type)
+	if failure.NonRetryable {
+		// a business error, which cannot be retried
+		return temporal.NewNonRetryableApplicationError(failure.Message, failure.Type, nil)
 	}
-	return result
+	// a transient error, which can be retried
+	return temporal.NewApplicationError(failure.Message, failure.Type)
 }
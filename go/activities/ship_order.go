@@ -13,14 +13,77 @@ func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
+// ShippingStages are the stages of handing an item to the carrier.
+var ShippingStages = []string{"Label Created", "Picked Up", "In Transit"}
+
+// ShipmentProgress is heartbeated by ShipOrder, so a retry after the worker is
+// lost resumes with the stage that was in progress.
+type ShipmentProgress struct {
+	// Completed is the number of ShippingStages completed
+	Completed int    `json:"completed"`
+	Stage     string `json:"stage,omitempty"`
+}
+
 func ShipOrder(ctx context.Context, input app.ShippingInput) error {
 	logger := activity.GetLogger(ctx)
 	logger.Info("Ship Order activity started", "orderId", input.Order.OrderId, "ItemId", input.Item.Id, "Item Description", input.Item.Description)
 
-	// simulate external API call
-	delayMs := rand.Intn(3001) + 1000
-	logger.Info("Shipping Delay Time", "delayMs", delayMs)
-	simulateExternalOperation(delayMs)
+	var progress ShipmentProgress
+	if activity.HasHeartbeatDetails(ctx) {
+		err := activity.GetHeartbeatDetails(ctx, &progress)
+		if err != nil {
+			logger.Warn("Unable to read shipment progress, starting over", "error", err)
+			progress = ShipmentProgress{}
+		} else {
+			logger.Info("Resuming shipment", "completed", progress.Completed, "stage", progress.Stage)
+		}
+	}
+
+	var stageDelay time.Duration
+	if input.Order.Scenario != nil {
+		stageDelay = input.Order.Scenario.ShippingStageDelay
+	}
+	for progress.Completed < len(ShippingStages) {
+		stage := ShippingStages[progress.Completed]
+		delay := stageDelay
+		if delay == 0 {
+			// simulate external API call
+			delay = time.Duration(rand.Intn(1001)+300) * time.Millisecond
+		}
+		logger.Info("Shipping stage", "stage", stage, "delay", delay)
+		err := waitForStage(ctx, delay, progress)
+		if err != nil {
+			logger.Info("Shipment cancelled", "stage", stage, "error", err)
+			return err
+		}
+		progress.Completed++
+		progress.Stage = stage
+		activity.RecordHeartbeat(ctx, progress)
+	}
 
+	if err := scenarioFailure(ctx, input.Order, "ShipOrder"); err != nil {
+		return err
+	}
+
+	activity.GetMetricsHandler(ctx).Counter(app.MetricItemsShipped).Inc(int64(input.Item.Quantity))
 	return nil
 }
+
+// waitForStage waits delay for a stage to complete, heartbeating progress so
+// the activity learns it was cancelled and the server that the worker is alive.
+func waitForStage(ctx context.Context, delay time.Duration, progress ShipmentProgress) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return nil
+		case <-ticker.C:
+			activity.RecordHeartbeat(ctx, progress)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package activities
+
+import (
+	"temporal-order-management/app"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func shipOrderStages(t *testing.T, heartbeatDetails *ShipmentProgress) []string {
+	var ts testsuite.WorkflowTestSuite
+	env := ts.NewTestActivityEnvironment()
+	env.RegisterActivity(ShipOrder)
+	if heartbeatDetails != nil {
+		env.SetHeartbeatDetails(*heartbeatDetails)
+	}
+	var stages []string
+	env.SetOnActivityHeartbeatListener(func(_ *activity.Info, details converter.EncodedValues) {
+		var progress ShipmentProgress
+		require.NoError(t, details.Get(&progress))
+		stages = append(stages, progress.Stage)
+	})
+
+	input := app.ShippingInput{
+		Order: app.OrderInput{OrderId: "1", Scenario: &app.Scenario{ShippingStageDelay: time.Millisecond}},
+		Item:  app.Item{Id: 1, Quantity: 1},
+	}
+	_, err := env.ExecuteActivity(ShipOrder, input)
+	require.NoError(t, err)
+	return stages
+}
+
+func TestShipOrderHeartbeatsStages(t *testing.T) {
+	// heartbeats are throttled, so only the first is sent straight away
+	stages := shipOrderStages(t, nil)
+	require.NotEmpty(t, stages)
+	require.Equal(t, ShippingStages[0], stages[0])
+}
+
+func TestShipOrderResumesFromHeartbeatDetails(t *testing.T) {
+	stages := shipOrderStages(t, &ShipmentProgress{Completed: 2, Stage: ShippingStages[1]})
+	require.Equal(t, []string{"In Transit"}, stages)
+}
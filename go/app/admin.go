@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
+
+// HealthChecker is the part of client.Client used for readiness.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context, request *client.CheckHealthRequest) (*client.CheckHealthResponse, error)
+}
+
+// Stopper is the part of worker.Worker used for draining.
+type Stopper interface {
+	Stop()
+}
+
+// AdminServer serves health, readiness and drain endpoints for a worker so it
+// can run under Kubernetes with rolling deploys:
+//   - GET /healthz is ok while the process is running
+//   - GET /readyz is ok while the client reaches the Temporal server and the
+//     worker is polling
+//   - POST /drain stops polling, waits for in-flight tasks to finish, up to the
+//     worker's WorkerStopTimeout, and then lets the worker exit
+//
+// Health and readiness are open to probes. Drain requests must carry the
+// shared secret as a bearer token, or come from the loopback interface, e.g.
+// a preStop hook, if there is no secret.
+type AdminServer struct {
+	client    HealthChecker
+	worker    Stopper
+	secret    string
+	started   atomic.Bool
+	draining  atomic.Bool
+	drainOnce sync.Once
+	drained   chan struct{}
+}
+
+// NewAdminServer creates an admin server for a worker created from c. Drain
+// requests are authenticated with secret, or only allowed from loopback if it
+// is empty.
+func NewAdminServer(c HealthChecker, w Stopper, secret string) *AdminServer {
+	return &AdminServer{client: c, worker: w, secret: secret, drained: make(chan struct{})}
+}
+
+// SetStarted marks the worker as polling once it has started.
+func (s *AdminServer) SetStarted() {
+	s.started.Store(true)
+}
+
+// Drain stops the worker, waiting for in-flight tasks. It is safe to call more
+// than once, later calls wait for the first drain to complete.
+func (s *AdminServer) Drain() {
+	s.draining.Store(true)
+	s.drainOnce.Do(func() {
+		log.Println("Draining worker, waiting for in-flight tasks")
+		s.worker.Stop()
+		log.Println("Worker drained")
+		close(s.drained)
+	})
+	<-s.drained
+}
+
+// Drained is closed once the worker has been drained.
+func (s *AdminServer) Drained() <-chan struct{} {
+	return s.drained
+}
+
+// Handler returns the admin endpoints.
+func (s *AdminServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminStatus(w, http.StatusOK, map[string]any{"status": "ok"})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		status := map[string]any{
+			"polling":  s.started.Load() && !s.draining.Load(),
+			"draining": s.draining.Load(),
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		_, err := s.client.CheckHealth(ctx, &client.CheckHealthRequest{})
+		status["connected"] = err == nil
+		if err != nil {
+			status["error"] = err.Error()
+		}
+
+		if err != nil || status["polling"] == false {
+			writeAdminStatus(w, http.StatusServiceUnavailable, status)
+			return
+		}
+		writeAdminStatus(w, http.StatusOK, status)
+	})
+	mux.HandleFunc("POST /drain", func(w http.ResponseWriter, r *http.Request) {
+		if !s.canDrain(r) {
+			log.Printf("Rejected drain request from %v", r.RemoteAddr)
+			writeAdminStatus(w, http.StatusUnauthorized, map[string]any{"status": "unauthorized"})
+			return
+		}
+		s.Drain()
+		writeAdminStatus(w, http.StatusOK, map[string]any{"status": "drained"})
+	})
+	return mux
+}
+
+func (s *AdminServer) canDrain(r *http.Request) bool {
+	if s.secret != "" {
+		return validSecret(r, s.secret)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Start serves the admin endpoints on address in the background.
+func (s *AdminServer) Start(address string) {
+	server := &http.Server{Addr: address, Handler: s.Handler()}
+	go func() {
+		log.Printf("✅ Admin server listening on %v", address)
+		err := server.ListenAndServe()
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalln("Unable to start admin server", err)
+		}
+	}()
+}
+
+func writeAdminStatus(w http.ResponseWriter, code int, status map[string]any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(status)
+}
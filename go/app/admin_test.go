@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+)
+
+type fakeHealthChecker struct {
+	err error
+}
+
+func (f *fakeHealthChecker) CheckHealth(ctx context.Context, request *client.CheckHealthRequest) (*client.CheckHealthResponse, error) {
+	return &client.CheckHealthResponse{}, f.err
+}
+
+type fakeStopper struct {
+	stops int
+}
+
+func (f *fakeStopper) Stop() {
+	f.stops++
+}
+
+// adminRequest sends a request to the admin server from the loopback
+// interface, with the bearer token if given.
+func adminRequest(s *AdminServer, method, path string, token ...string) int {
+	return adminRequestFrom(s, "127.0.0.1:4321", method, path, token...)
+}
+
+func adminRequestFrom(s *AdminServer, remoteAddr, method, path string, token ...string) int {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = remoteAddr
+	if len(token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+token[0])
+	}
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestAdminServerReadiness(t *testing.T) {
+	health := &fakeHealthChecker{}
+	s := NewAdminServer(health, &fakeStopper{}, "")
+
+	require.Equal(t, http.StatusOK, adminRequest(s, http.MethodGet, "/healthz"))
+	require.Equal(t, http.StatusServiceUnavailable, adminRequest(s, http.MethodGet, "/readyz"), "worker not started")
+
+	s.SetStarted()
+	require.Equal(t, http.StatusOK, adminRequest(s, http.MethodGet, "/readyz"))
+
+	health.err = errors.New("connection refused")
+	require.Equal(t, http.StatusServiceUnavailable, adminRequest(s, http.MethodGet, "/readyz"), "server unreachable")
+}
+
+func TestAdminServerDrain(t *testing.T) {
+	stopper := &fakeStopper{}
+	s := NewAdminServer(&fakeHealthChecker{}, stopper, "")
+	s.SetStarted()
+
+	require.Equal(t, http.StatusMethodNotAllowed, adminRequest(s, http.MethodGet, "/drain"))
+	require.Equal(t, http.StatusOK, adminRequest(s, http.MethodPost, "/drain"))
+	require.Equal(t, http.StatusServiceUnavailable, adminRequest(s, http.MethodGet, "/readyz"))
+	require.Equal(t, http.StatusOK, adminRequest(s, http.MethodGet, "/healthz"))
+
+	select {
+	case <-s.Drained():
+	default:
+		t.Fatal("Drained not closed after drain")
+	}
+
+	// Draining again, e.g. on interrupt after the drain request, stops only once
+	s.Drain()
+	require.Equal(t, 1, stopper.stops)
+}
+
+func TestAdminServerDrainRequiresSecretOrLoopback(t *testing.T) {
+	stopper := &fakeStopper{}
+	s := NewAdminServer(&fakeHealthChecker{}, stopper, "")
+	s.SetStarted()
+
+	// probes reach health and readiness from anywhere, but not drain
+	require.Equal(t, http.StatusOK, adminRequestFrom(s, "10.0.0.7:4321", http.MethodGet, "/healthz"))
+	require.Equal(t, http.StatusOK, adminRequestFrom(s, "10.0.0.7:4321", http.MethodGet, "/readyz"))
+	require.Equal(t, http.StatusUnauthorized, adminRequestFrom(s, "10.0.0.7:4321", http.MethodPost, "/drain"))
+	require.Equal(t, 0, stopper.stops)
+
+	s = NewAdminServer(&fakeHealthChecker{}, stopper, "s3cret")
+	require.Equal(t, http.StatusUnauthorized, adminRequest(s, http.MethodPost, "/drain"), "loopback without secret")
+	require.Equal(t, http.StatusUnauthorized, adminRequestFrom(s, "10.0.0.7:4321", http.MethodPost, "/drain", "wrong"))
+	require.Equal(t, 0, stopper.stops)
+	require.Equal(t, http.StatusOK, adminRequestFrom(s, "10.0.0.7:4321", http.MethodPost, "/drain", "s3cret"))
+	require.Equal(t, 1, stopper.stops)
+}
@@ -0,0 +1,251 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+)
+
+const (
+	RotationOutcomeAdopted      = "adopted"
+	RotationOutcomeCleared      = "cleared"
+	RotationOutcomeRejected     = "rejected"
+	RotationOutcomeUnauthorized = "unauthorized"
+)
+
+// APIKeyRotator holds the API key used by the client and swaps it at runtime.
+// A new key is only adopted once a test RPC made with it succeeds, otherwise
+// the current key stays in place.
+type APIKeyRotator struct {
+	key      atomic.Pointer[string]
+	mu       sync.Mutex // serializes rotations
+	validate func(ctx context.Context, key string) error
+	logger   *slog.Logger
+	metrics  client.MetricsHandler
+}
+
+// NewAPIKeyRotator creates a rotator starting with key. validate is called
+// with every new key before it is adopted.
+func NewAPIKeyRotator(key string, validate func(ctx context.Context, key string) error, logger *slog.Logger, metrics client.MetricsHandler) *APIKeyRotator {
+	if metrics == nil {
+		metrics = client.MetricsNopHandler
+	}
+	r := &APIKeyRotator{validate: validate, logger: logger, metrics: metrics}
+	r.key.Store(&key)
+	return r
+}
+
+// Key returns the current API key.
+func (r *APIKeyRotator) Key() string {
+	return *r.key.Load()
+}
+
+// Credentials returns client credentials that always use the current API key.
+func (r *APIKeyRotator) Credentials() client.Credentials {
+	return client.NewAPIKeyDynamicCredentials(func(context.Context) (string, error) {
+		return r.Key(), nil
+	})
+}
+
+// Rotate validates key and adopts it. An empty key clears the current key,
+// which the demo uses to show a worker losing access. If validation fails the
+// previous key is kept and the error is returned.
+func (r *APIKeyRotator) Rotate(ctx context.Context, key, source string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	previous := r.Key()
+	if key == "" {
+		r.key.Store(&key)
+		r.audit(RotationOutcomeCleared, source, previous, key, nil)
+		return nil
+	}
+
+	err := r.validate(ctx, key)
+	if err != nil {
+		r.audit(RotationOutcomeRejected, source, previous, key, err)
+		return err
+	}
+	r.key.Store(&key)
+	r.audit(RotationOutcomeAdopted, source, previous, key, nil)
+	return nil
+}
+
+// audit logs and counts a rotation attempt. Keys are only logged as
+// fingerprints.
+func (r *APIKeyRotator) audit(outcome, source, previous, key string, err error) {
+	r.metrics.WithTags(map[string]string{"outcome": outcome}).Counter("api_key_rotations").Inc(1)
+
+	attrs := []any{
+		"outcome", outcome,
+		"source", source,
+		"previousKey", fingerprint(previous),
+		"newKey", fingerprint(key),
+	}
+	if err != nil {
+		attrs = append(attrs, "error", err)
+		r.logger.Warn("API key rotation", attrs...)
+		return
+	}
+	r.logger.Info("API key rotation", attrs...)
+}
+
+func fingerprint(key string) string {
+	if key == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// APIKeyRotationHandler serves PUT requests with the new API key as the body.
+// Requests must carry the shared secret as a bearer token unless secret is
+// empty, in which case the server is expected to authenticate callers with
+// mTLS.
+func APIKeyRotationHandler(r *APIKeyRotator, secret string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /", func(w http.ResponseWriter, req *http.Request) {
+		if secret != "" && !validSecret(req, secret) {
+			r.audit(RotationOutcomeUnauthorized, req.RemoteAddr, r.Key(), "", nil)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(req.Body, 64*1024))
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+
+		source := req.RemoteAddr
+		if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+			source = req.TLS.PeerCertificates[0].Subject.CommonName + "@" + source
+		}
+		ctx, cancel := context.WithTimeout(req.Context(), 10*time.Second)
+		defer cancel()
+		err = r.Rotate(ctx, strings.TrimSpace(string(body)), source)
+		if err != nil {
+			http.Error(w, "API key rejected: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	return mux
+}
+
+func validSecret(req *http.Request, secret string) bool {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
+
+// validateAPIKey checks a key by describing the namespace with a client that
+// uses it.
+func validateAPIKey(options client.Options) func(ctx context.Context, key string) error {
+	return func(ctx context.Context, key string) error {
+		options.Credentials = client.NewAPIKeyStaticCredentials(key)
+		options.MetricsHandler = nil
+		c, err := client.DialContext(ctx, options)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+
+		namespace := options.Namespace
+		if namespace == "" {
+			namespace = client.DefaultNamespace
+		}
+		_, err = c.WorkflowService().DescribeNamespace(ctx, &workflowservice.DescribeNamespaceRequest{
+			Namespace: namespace,
+		})
+		return err
+	}
+}
+
+// StartAPIKeyRotationServer serves the rotation endpoint on
+// TEMPORAL_API_KEY_ROTATION_ADDRESS. Callers authenticate with the shared
+// secret in TEMPORAL_API_KEY_ROTATION_SECRET, or with a client certificate
+// signed by TEMPORAL_API_KEY_ROTATION_TLS_CA_PATH. Without either, or without
+// an API key to rotate, the endpoint is not started.
+func StartAPIKeyRotationServer(r *APIKeyRotator, logger *slog.Logger) {
+	if r == nil {
+		return
+	}
+	address := GetEnv("TEMPORAL_API_KEY_ROTATION_ADDRESS", "127.0.0.1:3333")
+	secret := GetEnv("TEMPORAL_API_KEY_ROTATION_SECRET", "")
+	tlsConfig, err := apiKeyRotationTLSConfig()
+	if err != nil {
+		logger.Error("API key rotation endpoint not started", "error", err)
+		return
+	}
+	if secret == "" && tlsConfig == nil {
+		logger.Warn("API key rotation endpoint not started, set TEMPORAL_API_KEY_ROTATION_SECRET or TEMPORAL_API_KEY_ROTATION_TLS_* to enable it")
+		return
+	}
+
+	server := &http.Server{
+		Addr:      address,
+		Handler:   APIKeyRotationHandler(r, secret),
+		TLSConfig: tlsConfig,
+	}
+	go func() {
+		logger.Info("API key rotation endpoint listening", "address", address, "mtls", tlsConfig != nil)
+		var err error
+		if tlsConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("API key rotation endpoint stopped", "error", err)
+		}
+	}()
+}
+
+// apiKeyRotationTLSConfig returns a TLS config requiring client certificates,
+// or nil if mTLS is not configured.
+func apiKeyRotationTLSConfig() (*tls.Config, error) {
+	certPath := GetEnv("TEMPORAL_API_KEY_ROTATION_TLS_CERT_PATH", "")
+	keyPath := GetEnv("TEMPORAL_API_KEY_ROTATION_TLS_KEY_PATH", "")
+	caPath := GetEnv("TEMPORAL_API_KEY_ROTATION_TLS_CA_PATH", "")
+	if certPath == "" && keyPath == "" && caPath == "" {
+		return nil, nil
+	}
+	if certPath == "" || keyPath == "" || caPath == "" {
+		return nil, fmt.Errorf("TEMPORAL_API_KEY_ROTATION_TLS_CERT_PATH, TEMPORAL_API_KEY_ROTATION_TLS_KEY_PATH and TEMPORAL_API_KEY_ROTATION_TLS_CA_PATH must all be set")
+	}
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := os.ReadFile(caPath)
+	if err != nil {
+		return nil, err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in %v", caPath)
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
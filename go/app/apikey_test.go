@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRotator() *APIKeyRotator {
+	validate := func(ctx context.Context, key string) error {
+		if key != "good-key" {
+			return errors.New("request unauthorized")
+		}
+		return nil
+	}
+	return NewAPIKeyRotator("old-key", validate, slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+}
+
+func putKey(handler http.Handler, key, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(key))
+	if secret != "" {
+		req.Header.Set("Authorization", "Bearer "+secret)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAPIKeyRotationHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		key    string
+		secret string
+		status int
+		want   string
+	}{
+		{"valid key is adopted", "good-key", "s3cret", http.StatusAccepted, "good-key"},
+		{"invalid key keeps the old key", "bad-key", "s3cret", http.StatusUnprocessableEntity, "old-key"},
+		{"empty key clears the key", "", "s3cret", http.StatusAccepted, ""},
+		{"missing secret is rejected", "good-key", "", http.StatusUnauthorized, "old-key"},
+		{"wrong secret is rejected", "good-key", "guess", http.StatusUnauthorized, "old-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rotator := newTestRotator()
+			rec := putKey(APIKeyRotationHandler(rotator, "s3cret"), tt.key, tt.secret)
+			require.Equal(t, tt.status, rec.Code)
+			require.Equal(t, tt.want, rotator.Key())
+		})
+	}
+}
+
+func TestAPIKeyRotationHandlerOnlyAcceptsPut(t *testing.T) {
+	rotator := newTestRotator()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("good-key"))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec := httptest.NewRecorder()
+	APIKeyRotationHandler(rotator, "s3cret").ServeHTTP(rec, req)
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, "old-key", rotator.Key())
+}
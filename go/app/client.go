@@ -1,11 +1,8 @@
 package app
 
 import (
-	"context"
-	"io"
 	"log"
 	"log/slog"
-	"net/http"
 	"os"
 	"time"
 
@@ -18,64 +15,42 @@ import (
 	tlog "go.temporal.io/sdk/log"
 )
 
-func GetClientOptions() client.Options {
+// GetClientOptions returns the client options of the long running processes,
+// which log to stdout, see LoadClientOptions. It exits if the options cannot
+// be loaded.
+func GetClientOptions(metrics client.MetricsHandler) (client.Options, *APIKeyRotator) {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
 	slog.SetDefault(logger)
 
-	clientOptions := envconfig.MustLoadDefaultClientOptions()
-	clientOptions.Logger = tlog.NewStructuredLogger(logger)
-	clientOptions.MetricsHandler = sdktally.NewMetricsHandler(newPrometheusScope(prometheus.Configuration{
-		ListenAddress: "0.0.0.0:9090",
-		TimerType:     "histogram",
-	}))
-
-	apiKey := GetEnv("TEMPORAL_API_KEY", "")
-	if apiKey != "" {
-		// "kms" service
-		http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-
-			if r.Method == http.MethodOptions {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-
-			if r.Method != http.MethodPut {
-				http.Error(w, "", http.StatusMethodNotAllowed)
-				return
-			}
-
-			body, err := io.ReadAll(r.Body)
-			if err != nil {
-				http.Error(w, "Failed to read request body", http.StatusBadRequest)
-				return
-			}
-			defer r.Body.Close()
-
-			apiKey = string(body)
-			log.Default().Println("API key updated")
-
-			w.WriteHeader(http.StatusAccepted)
-		})
+	clientOptions, rotator, err := LoadClientOptions(logger, metrics)
+	if err != nil {
+		log.Fatalln("Unable to load client options", err)
+	}
+	return clientOptions, rotator
+}
 
-		go func() {
-			err := http.ListenAndServe(":3333", nil) // make this an env variable
-			if err != nil {
-				log.Fatalln("Unable to start webserver", err)
-			}
-		}()
+// LoadClientOptions returns the client options from envconfig with the data
+// converter and the credentials in the TEMPORAL_ environment variables, logging
+// to logger. SDK metrics go to metrics, which may be nil. No listeners are
+// started here: the processes that serve metrics create the handler with
+// NewMetricsHandler, and the rotator, nil without an API key, is served with
+// StartAPIKeyRotationServer.
+func LoadClientOptions(logger *slog.Logger, metrics client.MetricsHandler) (client.Options, *APIKeyRotator, error) {
+	clientOptions, err := envconfig.LoadDefaultClientOptions()
+	if err != nil {
+		return client.Options{}, nil, err
+	}
+	clientOptions.Logger = tlog.NewStructuredLogger(logger)
+	clientOptions.DataConverter = GetDataConverter()
+	clientOptions.MetricsHandler = metrics
 
-		clientOptions.Credentials = client.NewAPIKeyDynamicCredentials(
-			func(context.Context) (string, error) {
-				return apiKey, nil
-			},
-		)
+	rotator, err := ConfigureCredentials(&clientOptions, "TEMPORAL_", logger)
+	if err != nil {
+		return client.Options{}, nil, err
 	}
-	return clientOptions
+	return clientOptions, rotator, nil
 }
 
 func GetEnv(key, fallback string) string {
@@ -85,6 +60,20 @@ func GetEnv(key, fallback string) string {
 	return fallback
 }
 
+// NewMetricsHandler serves the SDK and order metrics for Prometheus on address.
+func NewMetricsHandler(address string) client.MetricsHandler {
+	// Extend the default buckets so order latency, which takes minutes, is useful
+	var buckets []prometheus.HistogramObjective
+	for _, upper := range append(prometheus.DefaultHistogramBuckets(), 30, 60, 120, 300, 600) {
+		buckets = append(buckets, prometheus.HistogramObjective{Upper: upper})
+	}
+	return sdktally.NewMetricsHandler(newPrometheusScope(prometheus.Configuration{
+		ListenAddress:           address,
+		TimerType:               "histogram",
+		DefaultHistogramBuckets: buckets,
+	}))
+}
+
 func newPrometheusScope(c prometheus.Configuration) tally.Scope {
 	reporter, err := c.NewReporter(
 		prometheus.ConfigurationOptions{
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+const (
+	MetadataEncodingEncrypted = "binary/encrypted"
+	MetadataEncryptionKeyId   = "encryption-key-id"
+)
+
+// Keyring holds the encryption keys by key id. New payloads are encrypted with
+// the active key, older keys are kept so payloads encrypted before a key
+// rotation can still be decrypted.
+//
+// The keyring file is JSON with base64 encoded AES keys of 16, 24 or 32 bytes:
+//
+//	{"activeKeyId": "2025-11", "keys": {"2025-10": "...", "2025-11": "..."}}
+type Keyring struct {
+	ActiveKeyId string            `json:"activeKeyId"`
+	Keys        map[string]string `json:"keys"`
+}
+
+// LoadKeyringFile reads and validates a keyring file.
+func LoadKeyringFile(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keyring Keyring
+	err = json.Unmarshal(data, &keyring)
+	if err != nil {
+		return nil, fmt.Errorf("invalid keyring %v: %w", path, err)
+	}
+	if _, ok := keyring.Keys[keyring.ActiveKeyId]; !ok {
+		return nil, fmt.Errorf("invalid keyring %v: active key %q not found", path, keyring.ActiveKeyId)
+	}
+	return &keyring, nil
+}
+
+// EncryptionCodec encrypts payloads with AES-GCM, recording the id of the key
+// used in the payload metadata.
+type EncryptionCodec struct {
+	activeKeyId string
+	ciphers     map[string]cipher.AEAD
+}
+
+// NewEncryptionCodec creates a codec for the keys in keyring.
+func NewEncryptionCodec(keyring *Keyring) (*EncryptionCodec, error) {
+	if _, ok := keyring.Keys[keyring.ActiveKeyId]; !ok {
+		return nil, fmt.Errorf("active key %q not found in keyring", keyring.ActiveKeyId)
+	}
+	ciphers := make(map[string]cipher.AEAD, len(keyring.Keys))
+	for keyId, encodedKey := range keyring.Keys {
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("key %q is not base64: %w", keyId, err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", keyId, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", keyId, err)
+		}
+		ciphers[keyId] = aead
+	}
+	return &EncryptionCodec{activeKeyId: keyring.ActiveKeyId, ciphers: ciphers}, nil
+}
+
+// Encode implements converter.PayloadCodec.
+func (e *EncryptionCodec) Encode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	aead := e.ciphers[e.activeKeyId]
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		plaintext, err := p.Marshal()
+		if err != nil {
+			return payloads, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		_, err = rand.Read(nonce)
+		if err != nil {
+			return payloads, err
+		}
+		result[i] = &commonpb.Payload{
+			Metadata: map[string][]byte{
+				converter.MetadataEncoding: []byte(MetadataEncodingEncrypted),
+				MetadataEncryptionKeyId:    []byte(e.activeKeyId),
+			},
+			Data: aead.Seal(nonce, nonce, plaintext, nil),
+		}
+	}
+	return result, nil
+}
+
+// Decode implements converter.PayloadCodec. Payloads that are not encrypted
+// are returned unchanged, so orders started before encryption was enabled can
+// still be read.
+func (e *EncryptionCodec) Decode(payloads []*commonpb.Payload) ([]*commonpb.Payload, error) {
+	result := make([]*commonpb.Payload, len(payloads))
+	for i, p := range payloads {
+		if string(p.Metadata[converter.MetadataEncoding]) != MetadataEncodingEncrypted {
+			result[i] = p
+			continue
+		}
+		keyId := string(p.Metadata[MetadataEncryptionKeyId])
+		aead, ok := e.ciphers[keyId]
+		if !ok {
+			return payloads, fmt.Errorf("unknown encryption key %q", keyId)
+		}
+		if len(p.Data) < aead.NonceSize() {
+			return payloads, fmt.Errorf("encrypted payload too short")
+		}
+		nonce, ciphertext := p.Data[:aead.NonceSize()], p.Data[aead.NonceSize():]
+		plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return payloads, fmt.Errorf("unable to decrypt payload with key %q: %w", keyId, err)
+		}
+		result[i] = &commonpb.Payload{}
+		err = result[i].Unmarshal(plaintext)
+		if err != nil {
+			return payloads, err
+		}
+	}
+	return result, nil
+}
+
+// GetDataConverter returns a data converter that encrypts payloads with the
+// keyring in TEMPORAL_CODEC_KEYRING, or the default data converter if it is
+// not set.
+func GetDataConverter() converter.DataConverter {
+	keyringFile := GetEnv("TEMPORAL_CODEC_KEYRING", "")
+	if keyringFile == "" {
+		return converter.GetDefaultDataConverter()
+	}
+	codec, err := LoadEncryptionCodec(keyringFile)
+	if err != nil {
+		log.Fatalln("Unable to load payload codec", err)
+	}
+	return converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), codec)
+}
+
+// LoadEncryptionCodec creates a codec for the keys in a keyring file.
+func LoadEncryptionCodec(keyringFile string) (*EncryptionCodec, error) {
+	keyring, err := LoadKeyringFile(keyringFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewEncryptionCodec(keyring)
+}
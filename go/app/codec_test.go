@@ -0,0 +1,70 @@
+package app
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+func testKey(b byte) string {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = b
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func TestEncryptionCodecRoundTrip(t *testing.T) {
+	codec, err := NewEncryptionCodec(&Keyring{ActiveKeyId: "k1", Keys: map[string]string{"k1": testKey(1)}})
+	require.NoError(t, err)
+	dc := converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), codec)
+
+	input := OrderInput{OrderId: "123456", Address: "123 Main St"}
+	payload, err := dc.ToPayload(input)
+	require.NoError(t, err)
+	require.Equal(t, MetadataEncodingEncrypted, string(payload.Metadata[converter.MetadataEncoding]))
+	require.Equal(t, "k1", string(payload.Metadata[MetadataEncryptionKeyId]))
+	require.NotContains(t, string(payload.Data), "123 Main St")
+
+	var decoded OrderInput
+	require.NoError(t, dc.FromPayload(payload, &decoded))
+	require.Equal(t, input, decoded)
+}
+
+func TestEncryptionCodecKeyRotation(t *testing.T) {
+	oldCodec, err := NewEncryptionCodec(&Keyring{ActiveKeyId: "k1", Keys: map[string]string{"k1": testKey(1)}})
+	require.NoError(t, err)
+	newCodec, err := NewEncryptionCodec(&Keyring{ActiveKeyId: "k2", Keys: map[string]string{"k1": testKey(1), "k2": testKey(2)}})
+	require.NoError(t, err)
+
+	payload, err := converter.GetDefaultDataConverter().ToPayload("123 Main St")
+	require.NoError(t, err)
+	encrypted, err := oldCodec.Encode([]*commonpb.Payload{payload})
+	require.NoError(t, err)
+
+	// Payloads encrypted with the old key still decode after rotation
+	decoded, err := newCodec.Decode(encrypted)
+	require.NoError(t, err)
+	require.Equal(t, payload.Data, decoded[0].Data)
+
+	// New payloads use the new key, which the old keyring cannot decode
+	encrypted, err = newCodec.Encode([]*commonpb.Payload{payload})
+	require.NoError(t, err)
+	require.Equal(t, "k2", string(encrypted[0].Metadata[MetadataEncryptionKeyId]))
+	_, err = oldCodec.Decode(encrypted)
+	require.ErrorContains(t, err, `unknown encryption key "k2"`)
+}
+
+func TestEncryptionCodecDecodesPlainPayloads(t *testing.T) {
+	codec, err := NewEncryptionCodec(&Keyring{ActiveKeyId: "k1", Keys: map[string]string{"k1": testKey(1)}})
+	require.NoError(t, err)
+
+	payload, err := converter.GetDefaultDataConverter().ToPayload("123 Main St")
+	require.NoError(t, err)
+	decoded, err := codec.Decode([]*commonpb.Payload{payload})
+	require.NoError(t, err)
+	require.Equal(t, payload, decoded[0])
+}
@@ -0,0 +1,175 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.temporal.io/sdk/client"
+)
+
+// ConfigureCredentials sets up the API key and TLS client certificate from
+// the environment variables starting with prefix, e.g. "TEMPORAL_" or
+// "TEMPORAL_NEXUS_". The API key can be read from <prefix>API_KEY_FILE and the
+// cert/key pair in <prefix>TLS_CLIENT_CERT_PATH and <prefix>TLS_CLIENT_KEY_PATH
+// is watched when <prefix>TLS_CLIENT_CERT_WATCH is true. Both are reloaded when
+// the files change, e.g. when a mounted Kubernetes secret is updated.
+//
+// The returned rotator holds the API key, or is nil if no API key is used. A
+// key read from a changed file is validated and audited like any rotation.
+func ConfigureCredentials(clientOptions *client.Options, prefix string, logger *slog.Logger) (*APIKeyRotator, error) {
+	apiKey := GetEnv(prefix+"API_KEY", "")
+	apiKeyFile := GetEnv(prefix+"API_KEY_FILE", "")
+	if apiKeyFile != "" {
+		var err error
+		apiKey, err = ReadAPIKeyFile(apiKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if clientOptions.ConnectionOptions.TLS == nil {
+			// API keys require TLS, as envconfig enables for TEMPORAL_API_KEY
+			clientOptions.ConnectionOptions.TLS = &tls.Config{}
+		}
+	}
+
+	var rotator *APIKeyRotator
+	if apiKey != "" || apiKeyFile != "" {
+		rotator = NewAPIKeyRotator(apiKey, validateAPIKey(*clientOptions), logger, clientOptions.MetricsHandler)
+		clientOptions.Credentials = rotator.Credentials()
+	}
+	if apiKeyFile != "" {
+		err := watchAPIKeyFile(apiKeyFile, rotator, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if GetEnv(prefix+"TLS_CLIENT_CERT_WATCH", "") == "true" {
+		certPath := GetEnv(prefix+"TLS_CLIENT_CERT_PATH", "")
+		keyPath := GetEnv(prefix+"TLS_CLIENT_KEY_PATH", "")
+		if certPath == "" || keyPath == "" {
+			return nil, fmt.Errorf("%vTLS_CLIENT_CERT_WATCH requires %vTLS_CLIENT_CERT_PATH and %vTLS_CLIENT_KEY_PATH", prefix, prefix, prefix)
+		}
+		if clientOptions.ConnectionOptions.TLS == nil {
+			clientOptions.ConnectionOptions.TLS = &tls.Config{}
+		}
+		err := watchTLSKeyPair(certPath, keyPath, clientOptions.ConnectionOptions.TLS, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rotator, nil
+}
+
+// ReadAPIKeyFile returns the API key in path without surrounding whitespace.
+func ReadAPIKeyFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func watchAPIKeyFile(path string, rotator *APIKeyRotator, logger *slog.Logger) error {
+	return watchFiles([]string{path}, logger, func() {
+		key, err := ReadAPIKeyFile(path)
+		if err != nil {
+			logger.Error("Unable to read API key file", "path", path, "error", err)
+			return
+		}
+		if key == rotator.Key() {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		// A rejected key is logged by the rotator, the current key stays in use
+		_ = rotator.Rotate(ctx, key, "file:"+path)
+	})
+}
+
+// watchTLSKeyPair serves the client certificate from GetClientCertificate so
+// it can be swapped while the worker is running. Open connections keep the
+// certificate they were established with.
+func watchTLSKeyPair(certPath, keyPath string, tlsConfig *tls.Config, logger *slog.Logger) error {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return err
+	}
+	var current atomic.Pointer[tls.Certificate]
+	current.Store(&cert)
+
+	tlsConfig.Certificates = nil
+	tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		return current.Load(), nil
+	}
+
+	return watchFiles([]string{certPath, keyPath}, logger, func() {
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			// The cert and key are often not updated at the same time, wait
+			// for the other file before switching
+			logger.Warn("TLS client cert/key not reloaded", "cert", certPath, "key", keyPath, "error", err)
+			return
+		}
+		if bytes.Equal(cert.Certificate[0], current.Load().Certificate[0]) {
+			return
+		}
+		current.Store(&cert)
+		logger.Info("TLS client cert/key reloaded", "cert", certPath, "key", keyPath)
+	})
+}
+
+// watchFiles calls reload when any of paths may have changed. The parent
+// directories are watched rather than the files, so files replaced by a rename
+// or a symlink swap, as Kubernetes does for secret volumes, are picked up.
+func watchFiles(paths []string, logger *slog.Logger, reload func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	dirs := map[string]bool{}
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		err = watcher.Add(dir)
+		if err != nil {
+			watcher.Close()
+			return err
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+		// Writes usually come as several events, reload once they settle
+		var debounce <-chan time.Time
+		for {
+			select {
+			case _, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				debounce = time.After(100 * time.Millisecond)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Error("Credential file watcher error", "error", err)
+			case <-debounce:
+				debounce = nil
+				reload()
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,31 @@
+package app
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchAPIKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-key")
+	require.NoError(t, os.WriteFile(path, []byte("old-key\n"), 0o600))
+
+	rotator := newTestRotator()
+	require.NoError(t, watchAPIKeyFile(path, rotator, slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	// A valid key is adopted
+	require.NoError(t, os.WriteFile(path, []byte("good-key\n"), 0o600))
+	require.Eventually(t, func() bool { return rotator.Key() == "good-key" }, 5*time.Second, 20*time.Millisecond)
+
+	// A key replaced by a rename, as with secret volumes, that fails validation is ignored
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte("bad-key\n"), 0o600))
+	require.NoError(t, os.Rename(tmp, path))
+	time.Sleep(500 * time.Millisecond)
+	require.Equal(t, "good-key", rotator.Key())
+}
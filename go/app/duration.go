@@ -0,0 +1,41 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration written as a string such as "2s", in JSON as
+// well as YAML and TOML. A JSON number is read as nanoseconds, the way
+// time.Duration is encoded.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value any
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+	switch value := value.(type) {
+	case float64:
+		*d = Duration(value)
+		return nil
+	case string:
+		return d.UnmarshalText([]byte(value))
+	}
+	return fmt.Errorf("invalid duration %s", data)
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
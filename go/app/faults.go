@@ -0,0 +1,312 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// FaultLatency delays the activity by Delay before running it
+	FaultLatency = "latency"
+	// FaultError fails the activity with a retryable error
+	FaultError = "error"
+	// FaultNonRetryable fails the activity without retrying
+	FaultNonRetryable = "nonRetryable"
+	// FaultTimeout blocks the activity until it times out
+	FaultTimeout = "timeout"
+	// FaultHeartbeatStall drops the activity's heartbeats for Delay, or the
+	// whole attempt, so its heartbeat timeout fires
+	FaultHeartbeatStall = "heartbeatStall"
+
+	// FaultsKey is the memo key of the fault rules of an order, and the header
+	// they are passed to its activities and child workflows in.
+	FaultsKey = "faults"
+
+	// FaultErrorType is the error type of injected errors without a Type.
+	FaultErrorType = "InjectedFault"
+	// InvalidFaultRulesErrorType fails orders started with invalid rules in
+	// their memo.
+	InvalidFaultRulesErrorType = "InvalidFaultRules"
+)
+
+// FaultRule injects a fault into an activity of an order. Rules come from the
+// worker config, for every order, or from the "faults" memo of an order.
+type FaultRule struct {
+	// OrderId is the order the rule applies to, any order if empty
+	OrderId string `yaml:"orderId" toml:"orderId" json:"orderId,omitempty"`
+	// Activity is the activity type, e.g. CheckFraud, any activity if empty
+	Activity string `yaml:"activity" toml:"activity" json:"activity,omitempty"`
+	Fault    string `yaml:"fault" toml:"fault" json:"fault"`
+	// Attempts is the number of attempts the fault is injected into, every
+	// attempt if zero
+	Attempts int32    `yaml:"attempts" toml:"attempts" json:"attempts,omitempty"`
+	Delay    Duration `yaml:"delay" toml:"delay" json:"delay,omitempty"`
+	Type     string   `yaml:"type" toml:"type" json:"type,omitempty"`
+	Message  string   `yaml:"message" toml:"message" json:"message,omitempty"`
+}
+
+// FaultRules are checked in order, the first matching rule is injected.
+type FaultRules []FaultRule
+
+// LoadFaultRulesFile reads a YAML list of fault rules.
+func LoadFaultRulesFile(path string) (FaultRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules FaultRules
+	err = yaml.Unmarshal(data, &rules)
+	if err == nil {
+		err = rules.Validate()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid fault rules %v: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Validate checks every rule has a known fault and the delay it needs.
+func (r FaultRules) Validate() error {
+	for i, rule := range r {
+		switch rule.Fault {
+		case FaultError, FaultNonRetryable, FaultTimeout, FaultHeartbeatStall:
+		case FaultLatency:
+			if rule.Delay <= 0 {
+				return fmt.Errorf("fault rule %v: latency requires a delay", i)
+			}
+		default:
+			return fmt.Errorf("fault rule %v: unknown fault %q", i, rule.Fault)
+		}
+		if rule.Attempts < 0 || rule.Delay < 0 {
+			return fmt.Errorf("fault rule %v: negative attempts or delay", i)
+		}
+	}
+	return nil
+}
+
+// Match returns the first rule for attempt of activityType in order orderId,
+// or nil.
+func (r FaultRules) Match(orderId string, activityType string, attempt int32) *FaultRule {
+	for i, rule := range r {
+		if rule.OrderId != "" && rule.OrderId != orderId {
+			continue
+		}
+		if rule.Activity != "" && rule.Activity != activityType {
+			continue
+		}
+		if rule.Attempts > 0 && attempt > rule.Attempts {
+			continue
+		}
+		return &r[i]
+	}
+	return nil
+}
+
+func (r *FaultRule) error() error {
+	errorType := r.Type
+	if errorType == "" {
+		errorType = FaultErrorType
+	}
+	message := r.Message
+	if message == "" {
+		message = fmt.Sprintf("injected %v fault", r.Fault)
+	}
+	if r.Fault == FaultNonRetryable {
+		return temporal.NewNonRetryableApplicationError(message, errorType, nil)
+	}
+	return temporal.NewApplicationError(message, errorType)
+}
+
+// FaultInjector is a worker interceptor that injects faults into activities.
+// Rules in the memo of an order are passed on to the activities and child
+// workflows of the order in a header; they do not reach Nexus operations.
+type FaultInjector struct {
+	interceptor.WorkerInterceptorBase
+	rules         FaultRules
+	dataConverter converter.DataConverter
+}
+
+// NewFaultInjector creates a FaultInjector applying rules to every order.
+// dataConverter decodes the rules of an order, and should be the one the
+// order was started with.
+func NewFaultInjector(rules FaultRules, dataConverter converter.DataConverter) *FaultInjector {
+	if dataConverter == nil {
+		dataConverter = converter.GetDefaultDataConverter()
+	}
+	return &FaultInjector{rules: rules, dataConverter: dataConverter}
+}
+
+func (f *FaultInjector) InterceptActivity(ctx context.Context, next interceptor.ActivityInboundInterceptor) interceptor.ActivityInboundInterceptor {
+	i := &faultActivityInbound{injector: f}
+	i.Next = next
+	return i
+}
+
+func (f *FaultInjector) InterceptWorkflow(ctx workflow.Context, next interceptor.WorkflowInboundInterceptor) interceptor.WorkflowInboundInterceptor {
+	i := &faultWorkflowInbound{injector: f}
+	i.Next = next
+	return i
+}
+
+// activityRules returns the rules of the order the activity runs for,
+// followed by the worker's rules.
+func (f *FaultInjector) activityRules(ctx context.Context) FaultRules {
+	payload := interceptor.Header(ctx)[FaultsKey]
+	if payload == nil {
+		return f.rules
+	}
+	// the rules were validated when the order started
+	var rules FaultRules
+	err := f.dataConverter.FromPayload(payload, &rules)
+	if err != nil {
+		activity.GetLogger(ctx).Warn("Ignoring invalid fault rules", "error", err)
+		return f.rules
+	}
+	return append(rules, f.rules...)
+}
+
+type faultActivityInbound struct {
+	interceptor.ActivityInboundInterceptorBase
+	injector *FaultInjector
+}
+
+func (a *faultActivityInbound) Init(outbound interceptor.ActivityOutboundInterceptor) error {
+	o := &faultActivityOutbound{}
+	o.Next = outbound
+	return a.Next.Init(o)
+}
+
+func (a *faultActivityInbound) ExecuteActivity(ctx context.Context, in *interceptor.ExecuteActivityInput) (interface{}, error) {
+	info := activity.GetInfo(ctx)
+	rule := a.injector.activityRules(ctx).Match(faultOrderId(in.Args), info.ActivityType.Name, info.Attempt)
+	if rule == nil {
+		return a.Next.ExecuteActivity(ctx, in)
+	}
+
+	activity.GetLogger(ctx).Info("Injecting fault", "fault", rule.Fault, "attempt", info.Attempt)
+	switch rule.Fault {
+	case FaultLatency:
+		select {
+		case <-time.After(time.Duration(rule.Delay)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	case FaultError, FaultNonRetryable:
+		return nil, rule.error()
+	case FaultTimeout:
+		<-ctx.Done()
+		return nil, ctx.Err()
+	case FaultHeartbeatStall:
+		var until time.Time
+		if rule.Delay > 0 {
+			until = time.Now().Add(time.Duration(rule.Delay))
+		}
+		ctx = context.WithValue(ctx, heartbeatStallKey{}, until)
+	}
+	return a.Next.ExecuteActivity(ctx, in)
+}
+
+// heartbeatStallKey is the context key of the time heartbeats resume, zero
+// if they are dropped for the whole attempt.
+type heartbeatStallKey struct{}
+
+type faultActivityOutbound struct {
+	interceptor.ActivityOutboundInterceptorBase
+}
+
+func (a *faultActivityOutbound) RecordHeartbeat(ctx context.Context, details ...interface{}) {
+	if until, ok := ctx.Value(heartbeatStallKey{}).(time.Time); ok {
+		if until.IsZero() || time.Now().Before(until) {
+			return
+		}
+	}
+	a.Next.RecordHeartbeat(ctx, details...)
+}
+
+// faultOrderId returns the order id of the activity arguments.
+func faultOrderId(args []interface{}) string {
+	for _, arg := range args {
+		switch input := arg.(type) {
+		case OrderInput:
+			return input.OrderId
+		case ShippingInput:
+			return input.Order.OrderId
+		case ReturnInput:
+			return input.Shipment.Order.OrderId
+		}
+	}
+	return ""
+}
+
+// faultWorkflowInbound keeps the fault rules of the order, from its memo or,
+// for a child workflow, the header set by its parent.
+type faultWorkflowInbound struct {
+	interceptor.WorkflowInboundInterceptorBase
+	injector *FaultInjector
+	rules    *commonpb.Payload
+}
+
+func (w *faultWorkflowInbound) Init(outbound interceptor.WorkflowOutboundInterceptor) error {
+	o := &faultWorkflowOutbound{root: w}
+	o.Next = outbound
+	return w.Next.Init(o)
+}
+
+func (w *faultWorkflowInbound) ExecuteWorkflow(ctx workflow.Context, in *interceptor.ExecuteWorkflowInput) (interface{}, error) {
+	w.rules = workflow.GetInfo(ctx).Memo.GetFields()[FaultsKey]
+	if w.rules == nil {
+		// the parent validated the rules when it started
+		w.rules = interceptor.WorkflowHeader(ctx)[FaultsKey]
+		return w.Next.ExecuteWorkflow(ctx, in)
+	}
+
+	// Fail the order at once rather than run it without the faults it was
+	// started with. The memo is part of the workflow's input, so this replays
+	// the same way.
+	var rules FaultRules
+	err := w.injector.dataConverter.FromPayload(w.rules, &rules)
+	if err == nil {
+		err = rules.Validate()
+	}
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("invalid fault rules in memo: "+err.Error(), InvalidFaultRulesErrorType, err)
+	}
+	return w.Next.ExecuteWorkflow(ctx, in)
+}
+
+// faultWorkflowOutbound passes the rules on unchanged.
+type faultWorkflowOutbound struct {
+	interceptor.WorkflowOutboundInterceptorBase
+	root *faultWorkflowInbound
+}
+
+func (w *faultWorkflowOutbound) ExecuteActivity(ctx workflow.Context, activityType string, args ...interface{}) workflow.Future {
+	w.setHeader(ctx)
+	return w.Next.ExecuteActivity(ctx, activityType, args...)
+}
+
+func (w *faultWorkflowOutbound) ExecuteLocalActivity(ctx workflow.Context, activityType string, args ...interface{}) workflow.Future {
+	w.setHeader(ctx)
+	return w.Next.ExecuteLocalActivity(ctx, activityType, args...)
+}
+
+func (w *faultWorkflowOutbound) ExecuteChildWorkflow(ctx workflow.Context, childWorkflowType string, args ...interface{}) workflow.ChildWorkflowFuture {
+	w.setHeader(ctx)
+	return w.Next.ExecuteChildWorkflow(ctx, childWorkflowType, args...)
+}
+
+func (w *faultWorkflowOutbound) setHeader(ctx workflow.Context) {
+	if w.root.rules != nil {
+		interceptor.WorkflowHeader(ctx)[FaultsKey] = w.root.rules
+	}
+}
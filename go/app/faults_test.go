@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/interceptor"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+func faultyActivity(ctx context.Context, input OrderInput) (int32, error) {
+	activity.RecordHeartbeat(ctx, "working")
+	return activity.GetInfo(ctx).Attempt, nil
+}
+
+func faultyWorkflow(ctx workflow.Context, input OrderInput) (int32, error) {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Second,
+		RetryPolicy:         &temporal.RetryPolicy{InitialInterval: time.Millisecond, MaximumAttempts: 5},
+	})
+	var attempt int32
+	err := workflow.ExecuteActivity(ctx, faultyActivity, input).Get(ctx, &attempt)
+	return attempt, err
+}
+
+func faultyParentWorkflow(ctx workflow.Context, input OrderInput) (int32, error) {
+	var attempt int32
+	err := workflow.ExecuteChildWorkflow(ctx, faultyWorkflow, input).Get(ctx, &attempt)
+	return attempt, err
+}
+
+func newFaultTestEnv(t *testing.T, rules FaultRules, orderRules FaultRules) *testsuite.TestWorkflowEnvironment {
+	var suite testsuite.WorkflowTestSuite
+	if orderRules != nil {
+		payload, err := converter.GetDefaultDataConverter().ToPayload(orderRules)
+		require.NoError(t, err)
+		suite.SetHeader(&commonpb.Header{Fields: map[string]*commonpb.Payload{FaultsKey: payload}})
+	}
+	env := suite.NewTestWorkflowEnvironment()
+	env.SetWorkerOptions(worker.Options{
+		Interceptors: []interceptor.WorkerInterceptor{NewFaultInjector(rules, nil)},
+	})
+	env.RegisterWorkflow(faultyWorkflow)
+	env.RegisterWorkflow(faultyParentWorkflow)
+	env.RegisterActivity(faultyActivity)
+	return env
+}
+
+func TestFaultRulesMatch(t *testing.T) {
+	rules := FaultRules{
+		{OrderId: "1", Activity: "ShipOrder", Fault: FaultTimeout},
+		{Activity: "CheckFraud", Fault: FaultError, Attempts: 2},
+		{OrderId: "2", Fault: FaultLatency, Delay: Duration(time.Second)},
+	}
+	require.NoError(t, rules.Validate())
+
+	require.Equal(t, &rules[0], rules.Match("1", "ShipOrder", 7))
+	require.Nil(t, rules.Match("3", "ShipOrder", 1))
+	require.Equal(t, &rules[1], rules.Match("3", "CheckFraud", 2))
+	require.Nil(t, rules.Match("3", "CheckFraud", 3))
+	require.Equal(t, &rules[2], rules.Match("2", "CheckFraud", 3))
+
+	require.Error(t, FaultRules{{Fault: FaultLatency}}.Validate())
+	require.Error(t, FaultRules{{Fault: "outage"}}.Validate())
+}
+
+func TestFaultInjectorRetriesTransientErrors(t *testing.T) {
+	env := newFaultTestEnv(t, FaultRules{{Activity: "faultyActivity", Fault: FaultError, Attempts: 2}}, nil)
+
+	env.ExecuteWorkflow(faultyWorkflow, OrderInput{OrderId: "1"})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var attempt int32
+	require.NoError(t, env.GetWorkflowResult(&attempt))
+	require.Equal(t, int32(3), attempt)
+}
+
+func TestFaultInjectorOrderRulesReachChildWorkflows(t *testing.T) {
+	orderRules := FaultRules{{OrderId: "1", Activity: "faultyActivity", Fault: FaultNonRetryable, Type: "CarrierDown"}}
+	env := newFaultTestEnv(t, nil, orderRules)
+
+	env.ExecuteWorkflow(faultyParentWorkflow, OrderInput{OrderId: "1"})
+	require.True(t, env.IsWorkflowCompleted())
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, "CarrierDown", appErr.Type())
+
+	// other orders are not affected
+	env = newFaultTestEnv(t, nil, orderRules)
+	env.ExecuteWorkflow(faultyParentWorkflow, OrderInput{OrderId: "2"})
+	require.NoError(t, env.GetWorkflowError())
+}
+
+func TestFaultInjectorStallsHeartbeats(t *testing.T) {
+	for name, rules := range map[string]FaultRules{
+		"no fault": nil,
+		"stalled":  {{Fault: FaultHeartbeatStall}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			env := newFaultTestEnv(t, rules, nil)
+			heartbeats := 0
+			env.SetOnActivityHeartbeatListener(func(*activity.Info, converter.EncodedValues) {
+				heartbeats++
+			})
+
+			env.ExecuteWorkflow(faultyWorkflow, OrderInput{OrderId: "1"})
+			require.NoError(t, env.GetWorkflowError())
+			require.Equal(t, len(rules) == 0, heartbeats > 0)
+		})
+	}
+}
+
+func TestFaultRulesDelayIsADurationString(t *testing.T) {
+	var rules FaultRules
+	require.NoError(t, json.Unmarshal([]byte(`[{"fault": "latency", "delay": "2s"}, {"fault": "latency", "delay": 1000000000}]`), &rules))
+	require.Equal(t, Duration(2*time.Second), rules[0].Delay)
+	require.Equal(t, Duration(time.Second), rules[1].Delay)
+
+	data, err := json.Marshal(rules[0])
+	require.NoError(t, err)
+	require.JSONEq(t, `{"fault": "latency", "delay": "2s"}`, string(data))
+
+	require.Error(t, json.Unmarshal([]byte(`[{"fault": "latency", "delay": "soon"}]`), &rules))
+}
+
+func TestFaultInjectorRejectsInvalidMemoRules(t *testing.T) {
+	env := newFaultTestEnv(t, nil, nil)
+	require.NoError(t, env.SetMemoOnStart(map[string]interface{}{FaultsKey: FaultRules{{Fault: FaultLatency}}}))
+
+	env.ExecuteWorkflow(faultyWorkflow, OrderInput{OrderId: "1"})
+	require.True(t, env.IsWorkflowCompleted())
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, InvalidFaultRulesErrorType, appErr.Type())
+	env.AssertActivityNotCalled(t, "faultyActivity", mock.Anything, mock.Anything)
+}
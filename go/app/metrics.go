@@ -0,0 +1,14 @@
+package app
+
+// Order metrics emitted by the workflows and activities in addition to the SDK
+// metrics. Workflow metrics are tagged with the scenario.
+const (
+	MetricOrdersStarted   = "orders_started"
+	MetricOrdersCompleted = "orders_completed"
+	MetricOrdersFailed    = "orders_failed"
+	MetricOrdersCancelled = "orders_cancelled"
+	MetricOrderLatency    = "order_latency"
+	MetricCompensations   = "order_compensations"
+	MetricChargeFailures  = "charge_failures"
+	MetricItemsShipped    = "items_shipped"
+)
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"fmt"
+)
+
+// ReturnWorkflowId returns the workflow id used for the return of an order
+// item.
+func ReturnWorkflowId(orderId string, itemId int) string {
+	return fmt.Sprintf("return-%v-%v", orderId, itemId)
+}
+
+// Conditions of returned items, as reported when they are received.
+const (
+	ReturnConditionUnopened = "unopened"
+	ReturnConditionOpened   = "opened"
+	ReturnConditionDamaged  = "damaged"
+)
+
+var ReturnConditions = []string{ReturnConditionUnopened, ReturnConditionOpened, ReturnConditionDamaged}
+
+// ReturnInput starts a ReturnWorkflow for an item of a completed order. The
+// item travels back like a shipment, so it is described by the same input.
+type ReturnInput struct {
+	Shipment ShippingInput
+	// Payment is the captured payment of the order, part of which is refunded
+	Payment Payment
+	Reason  string
+}
+
+// NewReturnInput returns the input to return itemId of a completed order.
+func NewReturnInput(orderId string, order OrderOutput, itemId int, reason string) (ReturnInput, error) {
+	if order.Status != OrderStatusCompleted || order.Payment == nil || !order.Payment.Captured {
+		return ReturnInput{}, fmt.Errorf("order %v was not completed and paid for", orderId)
+	}
+	for _, item := range order.Items {
+		if item.Id == itemId {
+			return ReturnInput{
+				Shipment: ShippingInput{
+					Order: OrderInput{OrderId: orderId, Address: order.Address},
+					Item:  item,
+				},
+				Payment: *order.Payment,
+				Reason:  reason,
+			}, nil
+		}
+	}
+	return ReturnInput{}, fmt.Errorf("order %v has no item %v", orderId, itemId)
+}
+
+// Inspection is the outcome of inspecting a returned item.
+type Inspection struct {
+	Condition    string `json:"condition"`
+	Restock      bool   `json:"restock"`
+	RefundAmount int64  `json:"refundAmount"`
+}
+
+type ReturnOutput struct {
+	Status       string `json:"status"`
+	Condition    string `json:"condition,omitempty"`
+	RefundAmount int64  `json:"refundAmount"`
+	Restocked    bool   `json:"restocked"`
+}
@@ -1,26 +1,62 @@
 package app
 
-import "go.temporal.io/sdk/workflow"
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
 
 type Saga struct {
 	compensations []any
 	arguments     [][]any
 }
 
+// CompensationRecord is the outcome of a single compensation.
+type CompensationRecord struct {
+	Activity    string    `json:"activity"`
+	CompletedAt time.Time `json:"completedAt"`
+	Error       string    `json:"error,omitempty"`
+}
+
 func (s *Saga) AddCompensation(activity any, parameters ...any) {
 	s.compensations = append(s.compensations, activity)
 	s.arguments = append(s.arguments, parameters)
 }
 
-func (s Saga) Compensate(ctx workflow.Context) {
+func (s Saga) Compensate(ctx workflow.Context) []CompensationRecord {
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Saga compensations started")
 
 	// Compensate in the reverse order that activies were applied.
+	var records []CompensationRecord
 	for i := len(s.compensations) - 1; i >= 0; i-- {
+		record := CompensationRecord{Activity: activityName(s.compensations[i])}
+		outcome := "completed"
 		err := workflow.ExecuteActivity(ctx, s.compensations[i], s.arguments[i]...).Get(ctx, nil)
 		if err != nil {
 			logger.Error("Executing compensation failed", "Error", err)
+			record.Error = err.Error()
+			outcome = "failed"
 		}
+		record.CompletedAt = workflow.Now(ctx)
+		records = append(records, record)
+		workflow.GetMetricsHandler(ctx).WithTags(map[string]string{
+			"step":    record.Activity,
+			"outcome": outcome,
+		}).Counter(MetricCompensations).Inc(1)
+	}
+	return records
+}
+
+// activityName returns the activity type name the SDK registers for activity.
+func activityName(activity any) string {
+	if name, ok := activity.(string); ok {
+		return name
 	}
+	fullName := runtime.FuncForPC(reflect.ValueOf(activity).Pointer()).Name()
+	name := fullName[strings.LastIndex(fullName, ".")+1:]
+	return strings.TrimSuffix(name, "-fm")
 }
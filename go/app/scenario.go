@@ -0,0 +1,79 @@
+package app
+
+import (
+	"time"
+)
+
+const (
+	ShippingActivity = "activity"
+	ShippingChild    = "child"
+	ShippingNexus    = "nexus"
+
+	AwaitSignal = "signal"
+	AwaitUpdate = "update"
+
+	// StepWorkflow fails the workflow itself: it panics before shipping, like
+	// a bug in the workflow code.
+	StepWorkflow = "Workflow"
+)
+
+// Scenario declares how a demo order behaves. Scenarios are defined in the
+// scenario registry and carried in OrderInput, so the workflow and its
+// activities all see the same definition.
+type Scenario struct {
+	// Name is the scenario name, the workflow type without "OrderWorkflow"
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description" json:"description,omitempty"`
+	// Failure makes a step of the order fail
+	Failure *ScenarioFailure `yaml:"failure" json:"failure,omitempty"`
+	// Shipping ships each item with an activity, the default, a child
+	// workflow or a Nexus operation
+	Shipping string `yaml:"shipping" json:"shipping,omitempty"`
+	// AwaitAddress waits for an updated address, sent as a signal or an
+	// update, for up to AwaitTimeout before shipping
+	AwaitAddress string        `yaml:"awaitAddress" json:"awaitAddress,omitempty"`
+	AwaitTimeout time.Duration `yaml:"awaitTimeout" json:"awaitTimeout,omitempty"`
+	// SearchAttributes upserts the OrderStatus search attribute at each step
+	SearchAttributes bool `yaml:"searchAttributes" json:"searchAttributes,omitempty"`
+	// ShippingStageDelay is how long each stage of the carrier handoff takes
+	// in ShipOrder, a second or so if zero
+	ShippingStageDelay time.Duration `yaml:"shippingStageDelay" json:"shippingStageDelay,omitempty"`
+	// AwaitDelivery waits for the carrier to confirm delivery before the
+	// payment is captured
+	AwaitDelivery bool `yaml:"awaitDelivery" json:"awaitDelivery,omitempty"`
+	// Entity keeps the order running once it completes, as an order entity
+	// handling delivery events, returns, address corrections and notes
+	Entity bool `yaml:"entity" json:"entity,omitempty"`
+	// PickupDelay waits before shipping, long enough to roll out a new worker
+	// version while the order is in flight
+	PickupDelay time.Duration `yaml:"pickupDelay" json:"pickupDelay,omitempty"`
+	// VersioningBehavior is "pinned" or "auto-upgrade" for workers in a Worker
+	// Deployment, the worker default if empty
+	VersioningBehavior string `yaml:"versioningBehavior" json:"versioningBehavior,omitempty"`
+}
+
+// ScenarioFailure makes an activity, or the workflow, fail.
+type ScenarioFailure struct {
+	// Step is the activity that fails, e.g. ChargeCustomer, or StepWorkflow
+	Step string `yaml:"step" json:"step"`
+	// Attempts is the number of attempts that fail before the step succeeds,
+	// every attempt fails if zero
+	Attempts int32 `yaml:"attempts" json:"attempts,omitempty"`
+	// NonRetryable fails the step without retrying
+	NonRetryable bool   `yaml:"nonRetryable" json:"nonRetryable,omitempty"`
+	Type         string `yaml:"type" json:"type,omitempty"`
+	Message      string `yaml:"message" json:"message"`
+}
+
+// FailsStep returns the failure of step, or nil if the step does not fail.
+func (s *Scenario) FailsStep(step string) *ScenarioFailure {
+	if s == nil || s.Failure == nil || s.Failure.Step != step {
+		return nil
+	}
+	return s.Failure
+}
+
+// FailsAttempt reports whether attempt of the step fails.
+func (f *ScenarioFailure) FailsAttempt(attempt int32) bool {
+	return f.Attempts == 0 || attempt <= f.Attempts
+}
@@ -0,0 +1,25 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScenarioFailsStep(t *testing.T) {
+	var none *Scenario
+	require.Nil(t, none.FailsStep("ChargeCustomer"))
+
+	scenario := &Scenario{Name: "APIFailure", Failure: &ScenarioFailure{Step: "ChargeCustomer", Attempts: 4, Message: "unavailable"}}
+	require.Nil(t, scenario.FailsStep("ShipOrder"))
+
+	failure := scenario.FailsStep("ChargeCustomer")
+	require.NotNil(t, failure)
+	require.True(t, failure.FailsAttempt(1))
+	require.True(t, failure.FailsAttempt(4))
+	require.False(t, failure.FailsAttempt(5))
+
+	// without attempts every attempt fails
+	failure.Attempts = 0
+	require.True(t, failure.FailsAttempt(100))
+}
@@ -1,13 +1,43 @@
 package app
 
+import "fmt"
+
 const ShippingServiceName = "shipping-service"
 const ShippingOperationName = "ship-item"
 
+// ShippingInput ships an item of an order, or every item of a shipment if
+// Shipment is set.
 type ShippingInput struct {
-	Order OrderInput
-	Item  Item
+	Order    OrderInput
+	Item     Item
+	Shipment *Shipment `json:",omitempty"`
 }
 
 type ShippingOutput struct {
 	Message string
 }
+
+// Shipment is the items of an order shipped together from a warehouse.
+type Shipment struct {
+	// Id is unique within the order, the warehouse id
+	Id         string `json:"id"`
+	Warehouse  string `json:"warehouse"`
+	Items      Items  `json:"items"`
+	TrackingId string `json:"trackingId,omitempty"`
+}
+
+// CarrierShipmentId returns the id the carrier knows a shipment of an order
+// by. Orders that were not split into shipments go out as one shipment without
+// an id, known by the order id.
+func CarrierShipmentId(orderId string, shipmentId string) string {
+	if shipmentId == "" {
+		return orderId
+	}
+	return fmt.Sprintf("%v-%v", orderId, shipmentId)
+}
+
+// ShipmentWorkflowId returns the workflow id of the ShippingWorkflow shipping
+// a shipment of an order.
+func ShipmentWorkflowId(orderId string, shipmentId string) string {
+	return fmt.Sprintf("shipment-%v-%v", orderId, shipmentId)
+}
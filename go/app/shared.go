@@ -1,21 +1,95 @@
 package app
 
+import "time"
+
+// OrderWorkflowId returns the workflow id used for an order.
+func OrderWorkflowId(orderId string) string {
+	return "order-" + orderId
+}
+
 type OrderInput struct {
-	OrderId string
-	Address string
+	OrderId    string
+	Address    string
+	Customer   Customer
+	Items      []LineItem
+	PaymentRef string
+	// Scenario is the demo scenario of the order, looked up from the
+	// scenario registry by workflow type when not set by the starter
+	Scenario *Scenario `json:",omitempty"`
+	// ShippingTaskQueue is the task queue items are shipped on, set by the
+	// starter from SHIPPING_TASK_QUEUE. Shipping runs on the order's task
+	// queue if it is empty.
+	ShippingTaskQueue string `json:",omitempty"`
 }
 
+const (
+	OrderStatusCompleted = "completed"
+	OrderStatusCancelled = "cancelled"
+)
+
 type OrderOutput struct {
-	TrackingId string `json:"trackingId"`
-	Address    string `json:"address"`
+	// TrackingId is the tracking id of the first shipment
+	TrackingId  string   `json:"trackingId"`
+	TrackingIds []string `json:"trackingIds,omitempty"`
+	// Shipments are the shipments the order was split into, per warehouse
+	Shipments []Shipment `json:"shipments,omitempty"`
+	Address   string     `json:"address"`
+	Status    string     `json:"status"`
+	Reason    string     `json:"reason,omitempty"`
+	// Delivery is the carrier's confirmation of the first shipment, for
+	// scenarios that await it
+	Delivery   *DeliveryConfirmation  `json:"delivery,omitempty"`
+	Deliveries []DeliveryConfirmation `json:"deliveries,omitempty"`
+	// Items and Payment of a completed order, for returns
+	Items   Items    `json:"items,omitempty"`
+	Payment *Payment `json:"payment,omitempty"`
+}
+
+// DeliveryConfirmation is sent by the carrier once a shipment is delivered.
+type DeliveryConfirmation struct {
+	ShipmentId  string    `json:"shipmentId"`
+	DeliveredAt time.Time `json:"deliveredAt"`
+	ReceivedBy  string    `json:"receivedBy,omitempty"`
+}
+
+// Payment tracks how far payment for an order got.
+type Payment struct {
+	AuthorizationId string `json:"authorizationId"`
+	Amount          int64  `json:"amount"`
+	Captured        bool   `json:"captured"`
+}
+
+type Customer struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// LineItem is a single line of an order as placed by the customer. UnitPrice
+// is in cents; zero means "use the catalog price".
+type LineItem struct {
+	Sku       string `json:"sku"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice int64  `json:"unitPrice"`
 }
 
 type Items []Item
 
 type Item struct {
 	Id          int    `json:"id"`
+	Sku         string `json:"sku"`
 	Description string `json:"description"`
 	Quantity    int    `json:"quantity"`
+	UnitPrice   int64  `json:"unitPrice"`
+}
+
+// Total returns the order total in cents.
+func (p Items) Total() int64 {
+	var total int64
+	for _, item := range p {
+		total += item.UnitPrice * int64(item.Quantity)
+	}
+	return total
 }
 
 // Item sort methods
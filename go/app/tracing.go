@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/contrib/opentelemetry"
+)
+
+// ConfigureTracing adds an OpenTelemetry tracing interceptor to clientOptions,
+// which is also used by workers created from the client. The exporter is
+// chosen with OTEL_TRACES_EXPORTER:
+//   - stdout: spans are printed as JSON
+//   - otlp: spans are sent to OTEL_EXPORTER_OTLP_ENDPOINT over gRPC
+//   - file: spans are written as JSON to OTEL_TRACES_FILE, default traces.json
+//
+// Tracing is disabled if OTEL_TRACES_EXPORTER is not set. The returned function
+// flushes pending spans and should be called before exiting.
+func ConfigureTracing(clientOptions *client.Options, serviceName string) func() {
+	exporterName := GetEnv("OTEL_TRACES_EXPORTER", "")
+	if exporterName == "" || exporterName == "none" {
+		return func() {}
+	}
+
+	ctx := context.Background()
+	exporter, err := newSpanExporter(ctx, exporterName)
+	if err != nil {
+		log.Fatalln("Unable to create trace exporter", err)
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		log.Fatalln("Unable to create trace resource", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+
+	tracingInterceptor, err := opentelemetry.NewTracingInterceptor(opentelemetry.TracerOptions{
+		Tracer: provider.Tracer("temporal-order-management"),
+	})
+	if err != nil {
+		log.Fatalln("Unable to create tracing interceptor", err)
+	}
+	clientOptions.Interceptors = append(clientOptions.Interceptors, tracingInterceptor)
+	log.Printf("OpenTelemetry tracing enabled, exporting to %v", exporterName)
+
+	return func() {
+		err := provider.Shutdown(context.Background())
+		if err != nil {
+			log.Println("Unable to flush traces", err)
+		}
+	}
+}
+
+func newSpanExporter(ctx context.Context, name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "otlp":
+		return otlptracegrpc.New(ctx)
+	case "file":
+		f, err := os.OpenFile(GetEnv("OTEL_TRACES_FILE", "traces.json"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(f))
+	default:
+		return nil, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q, expected stdout, otlp or file", name)
+	}
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+)
+
+func TestConfigureTracing(t *testing.T) {
+	t.Setenv("OTEL_TRACES_EXPORTER", "none")
+	var options client.Options
+	shutdown := ConfigureTracing(&options, "test")
+	shutdown()
+	require.Empty(t, options.Interceptors)
+
+	t.Setenv("OTEL_TRACES_EXPORTER", "file")
+	t.Setenv("OTEL_TRACES_FILE", filepath.Join(t.TempDir(), "traces.json"))
+	shutdown = ConfigureTracing(&options, "test")
+	shutdown()
+	require.Len(t, options.Interceptors, 1)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"temporal-order-management/app"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+)
+
+type progressEvent struct {
+	Progress int    `json:"progress"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// streamProgress sends the order progress as Server-Sent Events once a second
+// until the order completes or fails, like the Python UI's /stream_progress.
+func (s *server) streamProgress(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	ctx := r.Context()
+	workflowId := app.OrderWorkflowId(r.PathValue("id"))
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		event := s.progress(ctx, workflowId)
+		data, _ := json.Marshal(event)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+
+		if event.Error != "" || event.Progress >= 100 || event.Status != enums.WORKFLOW_EXECUTION_STATUS_RUNNING.String() {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *server) progress(ctx context.Context, workflowId string) progressEvent {
+	desc, err := s.client.DescribeWorkflowExecution(ctx, workflowId, "")
+	if err != nil {
+		return progressEvent{Error: err.Error()}
+	}
+	status := desc.GetWorkflowExecutionInfo().GetStatus()
+	event := progressEvent{Status: status.String()}
+
+	value, err := s.client.QueryWorkflow(ctx, workflowId, "", "getProgress")
+	if err == nil {
+		err = value.Get(&event.Progress)
+	}
+	if err != nil {
+		event.Error = err.Error()
+		return event
+	}
+
+	switch status {
+	case enums.WORKFLOW_EXECUTION_STATUS_RUNNING, enums.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+	default:
+		event.Error = fmt.Sprintf("Workflow %v: %v", status, workflowId)
+	}
+	return event
+}
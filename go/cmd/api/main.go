@@ -0,0 +1,44 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"temporal-order-management/app"
+	"temporal-order-management/workflows"
+
+	"go.temporal.io/sdk/client"
+)
+
+// REST API for orders, an alternative to the Python UI talking to Temporal directly.
+func main() {
+	// The API runs next to the workers, so it serves metrics on its own address
+	// and leaves the API key rotation endpoint to the worker
+	co, _ := app.GetClientOptions(app.NewMetricsHandler(app.GetEnv("API_METRICS_ADDRESS", "0.0.0.0:9093")))
+	shutdownTracing := app.ConfigureTracing(&co, "orders-api")
+	defer shutdownTracing()
+	c, err := client.Dial(co)
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	log.Printf("✅ Client connected to %v in namespace '%v'", co.HostPort, co.Namespace)
+	defer c.Close()
+
+	err = workflows.LoadScenarios()
+	if err != nil {
+		log.Fatalln("Unable to load scenarios", err)
+	}
+
+	s := &server{
+		client:            c,
+		taskQueue:         app.GetEnv("TEMPORAL_TASK_QUEUE", "orders"),
+		shippingTaskQueue: app.GetEnv("SHIPPING_TASK_QUEUE", ""),
+		allowedOrigin:     app.GetEnv("API_ALLOWED_ORIGIN", ""),
+	}
+
+	address := app.GetEnv("API_ADDRESS", "localhost:8080")
+	log.Printf("✅ Order API listening on %v", address)
+	err = http.ListenAndServe(address, s.routes())
+	if err != nil {
+		log.Fatalln("Unable to start order API", err)
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"temporal-order-management/app"
+	"temporal-order-management/messages"
+	"temporal-order-management/workflows"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+)
+
+type server struct {
+	client    client.Client
+	taskQueue string
+	// shippingTaskQueue is the task queue orders ship on, empty to ship on
+	// taskQueue
+	shippingTaskQueue string
+	allowedOrigin     string
+}
+
+type createOrderRequest struct {
+	OrderId    string         `json:"orderId"`
+	Scenario   string         `json:"scenario"`
+	Address    string         `json:"address"`
+	Customer   app.Customer   `json:"customer"`
+	Items      []app.LineItem `json:"items"`
+	PaymentRef string         `json:"paymentRef"`
+	// Faults are injected into the activities of the order
+	Faults app.FaultRules `json:"faults,omitempty"`
+}
+
+type orderResponse struct {
+	OrderId     string                `json:"orderId"`
+	WorkflowId  string                `json:"workflowId"`
+	RunId       string                `json:"runId"`
+	Scenario    string                `json:"scenario,omitempty"`
+	Status      string                `json:"status,omitempty"`
+	OrderStatus *messages.OrderStatus `json:"orderStatus,omitempty"`
+	Result      *app.OrderOutput      `json:"result,omitempty"`
+	Failure     *failure              `json:"failure,omitempty"`
+}
+
+// failure describes why an order failed; Type and Details are set for
+// application errors such as OutOfStock.
+type failure struct {
+	Message string `json:"message"`
+	Type    string `json:"type,omitempty"`
+	Details any    `json:"details,omitempty"`
+}
+
+type addressRequest struct {
+	Address string `json:"address"`
+	// Mode is "update" (default) or "signal".
+	Mode string `json:"mode"`
+}
+
+type cancelRequest struct {
+	Reason string `json:"reason"`
+	// Mode is "update" (default), "signal", or "workflow" for a workflow
+	// cancellation request.
+	Mode string `json:"mode"`
+}
+
+type messageResponse struct {
+	Result string `json:"result"`
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /orders", s.createOrder)
+	mux.HandleFunc("GET /orders/{id}", s.getOrder)
+	mux.HandleFunc("POST /orders/{id}/address", s.updateAddress)
+	mux.HandleFunc("POST /orders/{id}/cancel", s.cancelOrder)
+	mux.HandleFunc("POST /orders/{id}/notes", s.addNote)
+	mux.HandleFunc("POST /orders/{id}/delivery-events", s.addDeliveryEvent)
+	mux.HandleFunc("GET /orders/{id}/events", s.streamProgress)
+	mux.HandleFunc("POST /orders/{id}/returns", s.createReturn)
+	mux.HandleFunc("GET /orders/{id}/returns/{itemId}", s.getReturn)
+	mux.HandleFunc("POST /orders/{id}/returns/{itemId}/received", s.receiveReturn)
+	return s.cors(mux)
+}
+
+func (s *server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.allowedOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", s.allowedOrigin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) createOrder(w http.ResponseWriter, r *http.Request) {
+	var req createOrderRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Scenario == "" {
+		req.Scenario = "HappyPath"
+	}
+	workflowType, ok := workflows.ScenarioWorkflowType(req.Scenario)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown scenario %v", req.Scenario))
+		return
+	}
+	if req.OrderId == "" {
+		req.OrderId = fmt.Sprintf("%06d", rand.Intn(1000000))
+	}
+	err = req.Faults.Validate()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// the order carries its scenario, so changes to the registry do not
+	// affect it once started
+	scenario, _ := workflows.LookupScenario(workflowType)
+	input := app.OrderInput{
+		OrderId:           req.OrderId,
+		Address:           req.Address,
+		Customer:          req.Customer,
+		Items:             req.Items,
+		PaymentRef:        req.PaymentRef,
+		Scenario:          &scenario,
+		ShippingTaskQueue: s.shippingTaskQueue,
+	}
+	options := client.StartWorkflowOptions{
+		ID:                       app.OrderWorkflowId(req.OrderId),
+		TaskQueue:                s.taskQueue,
+		WorkflowIDConflictPolicy: enums.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	}
+	if len(req.Faults) > 0 {
+		options.Memo = map[string]interface{}{app.FaultsKey: req.Faults}
+	}
+	run, err := s.client.ExecuteWorkflow(r.Context(), options, workflowType, input)
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	if errors.As(err, &alreadyStarted) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, orderResponse{
+		OrderId:    req.OrderId,
+		WorkflowId: run.GetID(),
+		RunId:      run.GetRunID(),
+		Scenario:   req.Scenario,
+	})
+}
+
+func (s *server) getOrder(w http.ResponseWriter, r *http.Request) {
+	orderId := r.PathValue("id")
+	workflowId := app.OrderWorkflowId(orderId)
+
+	desc, err := s.client.DescribeWorkflowExecution(r.Context(), workflowId, "")
+	if err != nil {
+		writeTemporalError(w, err)
+		return
+	}
+	info := desc.GetWorkflowExecutionInfo()
+	resp := orderResponse{
+		OrderId:    orderId,
+		WorkflowId: workflowId,
+		RunId:      info.GetExecution().GetRunId(),
+		Status:     info.GetStatus().String(),
+	}
+
+	// Workers without getOrderStatus, e.g. other SDKs, only leave it out
+	value, err := s.client.QueryWorkflow(r.Context(), workflowId, "", "getOrderStatus")
+	if err == nil {
+		var status messages.OrderStatus
+		if value.Get(&status) == nil {
+			resp.OrderStatus = &status
+		}
+	}
+
+	if info.GetStatus() != enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		var output app.OrderOutput
+		err = s.client.GetWorkflow(r.Context(), workflowId, "").Get(r.Context(), &output)
+		if err != nil {
+			resp.Failure = toFailure(err)
+		} else {
+			resp.Result = &output
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *server) updateAddress(w http.ResponseWriter, r *http.Request) {
+	var req addressRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	workflowId := app.OrderWorkflowId(r.PathValue("id"))
+	input := messages.UpdateOrderInput{Address: req.Address}
+	s.sendMessage(w, r.Context(), workflowId, req.Mode, "UpdateOrder", input)
+}
+
+func (s *server) cancelOrder(w http.ResponseWriter, r *http.Request) {
+	var req cancelRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	workflowId := app.OrderWorkflowId(r.PathValue("id"))
+	if req.Mode == "workflow" {
+		err = s.client.CancelWorkflow(r.Context(), workflowId, "")
+		if err != nil {
+			writeTemporalError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, messageResponse{Result: "Cancellation requested"})
+		return
+	}
+
+	input := messages.CancelOrderInput{Reason: req.Reason}
+	s.sendMessage(w, r.Context(), workflowId, req.Mode, "CancelOrder", input)
+}
+
+// addNote adds a customer note to an order entity.
+func (s *server) addNote(w http.ResponseWriter, r *http.Request) {
+	var input messages.AddNoteInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	workflowId := app.OrderWorkflowId(r.PathValue("id"))
+	s.sendMessage(w, r.Context(), workflowId, "update", "AddNote", input)
+}
+
+// addDeliveryEvent passes a carrier delivery event on to an order entity.
+func (s *server) addDeliveryEvent(w http.ResponseWriter, r *http.Request) {
+	var input messages.DeliveryEventInput
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	workflowId := app.OrderWorkflowId(r.PathValue("id"))
+	s.sendMessage(w, r.Context(), workflowId, "signal", "DeliveryEvent", input)
+}
+
+// sendMessage sends a signal or executes an update, depending on mode.
+func (s *server) sendMessage(w http.ResponseWriter, ctx context.Context, workflowId string, mode string, name string, input any) {
+	switch mode {
+	case "signal":
+		err := s.client.SignalWorkflow(ctx, workflowId, "", name, input)
+		if err != nil {
+			writeTemporalError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, messageResponse{Result: name + " signal sent"})
+	case "", "update":
+		handle, err := s.client.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+			WorkflowID:   workflowId,
+			UpdateName:   name,
+			Args:         []any{input},
+			WaitForStage: client.WorkflowUpdateStageCompleted,
+		})
+		if err != nil {
+			writeTemporalError(w, err)
+			return
+		}
+		var result string
+		err = handle.Get(ctx, &result)
+		var appErr *temporal.ApplicationError
+		if errors.As(err, &appErr) {
+			// rejected by the update validator
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return
+		}
+		if err != nil {
+			writeTemporalError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, messageResponse{Result: result})
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown mode %v", mode))
+	}
+}
+
+func toFailure(err error) *failure {
+	f := &failure{Message: err.Error()}
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) {
+		f.Type = appErr.Type()
+		if appErr.HasDetails() {
+			var details any
+			if appErr.Details(&details) == nil {
+				f.Details = details
+			}
+		}
+	}
+	return f
+}
+
+func writeTemporalError(w http.ResponseWriter, err error) {
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusBadGateway, err)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
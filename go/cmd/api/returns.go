@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"temporal-order-management/app"
+	"temporal-order-management/messages"
+	"temporal-order-management/workflows"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+)
+
+type createReturnRequest struct {
+	ItemId int    `json:"itemId"`
+	Reason string `json:"reason"`
+}
+
+type returnResponse struct {
+	OrderId      string                 `json:"orderId"`
+	ItemId       int                    `json:"itemId"`
+	WorkflowId   string                 `json:"workflowId"`
+	RunId        string                 `json:"runId,omitempty"`
+	Status       string                 `json:"status,omitempty"`
+	ReturnStatus *messages.ReturnStatus `json:"returnStatus,omitempty"`
+	Result       *app.ReturnOutput      `json:"result,omitempty"`
+	Failure      *failure               `json:"failure,omitempty"`
+}
+
+// createReturn starts a ReturnWorkflow for an item of a completed order, or
+// asks an order entity to start it.
+func (s *server) createReturn(w http.ResponseWriter, r *http.Request) {
+	var req createReturnRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	orderId := r.PathValue("id")
+	workflowId := app.OrderWorkflowId(orderId)
+
+	desc, err := s.client.DescribeWorkflowExecution(r.Context(), workflowId, "")
+	if err != nil {
+		writeTemporalError(w, err)
+		return
+	}
+	switch desc.GetWorkflowExecutionInfo().GetStatus() {
+	case enums.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+	case enums.WORKFLOW_EXECUTION_STATUS_RUNNING:
+		// an order entity starts its own returns
+		input := messages.RequestReturnInput{ItemId: req.ItemId, Reason: req.Reason}
+		s.sendMessage(w, r.Context(), workflowId, "update", "RequestReturn", input)
+		return
+	default:
+		writeError(w, http.StatusConflict, fmt.Errorf("order %v is not completed", orderId))
+		return
+	}
+	var order app.OrderOutput
+	err = s.client.GetWorkflow(r.Context(), workflowId, "").Get(r.Context(), &order)
+	if err != nil {
+		writeTemporalError(w, err)
+		return
+	}
+	input, err := app.NewReturnInput(orderId, order, req.ItemId, req.Reason)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	options := client.StartWorkflowOptions{
+		ID:                       app.ReturnWorkflowId(orderId, req.ItemId),
+		TaskQueue:                s.taskQueue,
+		WorkflowIDConflictPolicy: enums.WORKFLOW_ID_CONFLICT_POLICY_FAIL,
+	}
+	run, err := s.client.ExecuteWorkflow(r.Context(), options, workflows.ReturnWorkflow, input)
+	var alreadyStarted *serviceerror.WorkflowExecutionAlreadyStarted
+	if errors.As(err, &alreadyStarted) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, returnResponse{
+		OrderId:    orderId,
+		ItemId:     req.ItemId,
+		WorkflowId: run.GetID(),
+		RunId:      run.GetRunID(),
+	})
+}
+
+func (s *server) getReturn(w http.ResponseWriter, r *http.Request) {
+	orderId := r.PathValue("id")
+	itemId, err := strconv.Atoi(r.PathValue("itemId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	workflowId := app.ReturnWorkflowId(orderId, itemId)
+
+	desc, err := s.client.DescribeWorkflowExecution(r.Context(), workflowId, "")
+	if err != nil {
+		writeTemporalError(w, err)
+		return
+	}
+	info := desc.GetWorkflowExecutionInfo()
+	resp := returnResponse{
+		OrderId:    orderId,
+		ItemId:     itemId,
+		WorkflowId: workflowId,
+		RunId:      info.GetExecution().GetRunId(),
+		Status:     info.GetStatus().String(),
+	}
+
+	value, err := s.client.QueryWorkflow(r.Context(), workflowId, "", "getReturnStatus")
+	if err == nil {
+		var status messages.ReturnStatus
+		if value.Get(&status) == nil {
+			resp.ReturnStatus = &status
+		}
+	}
+
+	if info.GetStatus() != enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		var output app.ReturnOutput
+		err = s.client.GetWorkflow(r.Context(), workflowId, "").Get(r.Context(), &output)
+		if err != nil {
+			resp.Failure = toFailure(err)
+		} else {
+			resp.Result = &output
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// receiveReturn signals that the returned item arrived at the warehouse.
+func (s *server) receiveReturn(w http.ResponseWriter, r *http.Request) {
+	var req messages.ReturnReceivedInput
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if !slices.Contains(app.ReturnConditions, req.Condition) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown condition %q, expected one of %v", req.Condition, app.ReturnConditions))
+		return
+	}
+	itemId, err := strconv.Atoi(r.PathValue("itemId"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	workflowId := app.ReturnWorkflowId(r.PathValue("id"), itemId)
+	s.sendMessage(w, r.Context(), workflowId, "signal", "ReturnReceived", req)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"temporal-order-management/activities"
+	"temporal-order-management/app"
+	"time"
+
+	"go.temporal.io/sdk/client"
+)
+
+// A local carrier for CARRIER_URL. Shipments are confirmed by POSTing to
+// /shipments/{id}/delivered or /shipments/{id}/failed, or after
+// CARRIER_DELIVERY_DELAY if set.
+func main() {
+	co, _ := app.GetClientOptions(nil)
+	c, err := client.Dial(co)
+	if err != nil {
+		log.Fatalln("Unable to create client", err)
+	}
+	defer c.Close()
+
+	var deliveryDelay time.Duration
+	if delay := app.GetEnv("CARRIER_DELIVERY_DELAY", ""); delay != "" {
+		deliveryDelay, err = time.ParseDuration(delay)
+		if err != nil {
+			log.Fatalln("Invalid CARRIER_DELIVERY_DELAY", err)
+		}
+	}
+	simulator := activities.NewCarrierSimulator(c, deliveryDelay)
+
+	address := app.GetEnv("CARRIER_ADDRESS", "localhost:8083")
+	log.Printf("✅ Carrier simulator listening on %v", address)
+	err = http.ListenAndServe(address, simulator.Handler())
+	if err != nil {
+		log.Fatalln("Unable to start carrier simulator", err)
+	}
+}
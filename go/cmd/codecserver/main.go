@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"temporal-order-management/app"
+
+	"go.temporal.io/sdk/converter"
+)
+
+// A codec server so the Temporal Web UI and CLI can decode encrypted order payloads.
+func main() {
+	codec, err := app.LoadEncryptionCodec(app.GetEnv("TEMPORAL_CODEC_KEYRING", "keyring.json"))
+	if err != nil {
+		log.Fatalln("Unable to load payload codec", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", converter.NewPayloadCodecHTTPHandler(codec))
+	handler := cors(app.GetEnv("CODEC_ALLOWED_ORIGIN", "http://localhost:8233"), mux)
+
+	address := app.GetEnv("CODEC_SERVER_ADDRESS", "localhost:8082")
+	log.Printf("✅ Codec server listening on %v", address)
+	err = http.ListenAndServe(address, handler)
+	if err != nil {
+		log.Fatalln("Unable to start codec server", err)
+	}
+}
+
+// cors allows the Web UI, which calls the codec server from the browser, to
+// send its namespace header and credentials.
+func cors(allowedOrigin string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if allowedOrigin != "" && r.Header.Get("Origin") == allowedOrigin {
+			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Namespace, Authorization")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"temporal-order-management/app"
+	"temporal-order-management/workflows"
+
+	"go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/api/temporalproto"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/contrib/envconfig"
+	tlog "go.temporal.io/sdk/log"
+)
+
+const usage = `Usage: histories <command> [flags]
+
+Commands:
+  export -workflow-id <id> [-run-id <id>] [-dir <dir>] [-name <file>]
+         save the history of a workflow as JSON, by default to workflows/testdata/histories
+  replay [-dir <dir>] [-patch-recoverable-failure]
+         replay every JSON history in a directory against the current workflow code, with the
+         RecoverableFailure patch if PATCH_RECOVERABLE_FAILURE is true, like the worker
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(os.Args[2:])
+	case "replay":
+		err = runReplay(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	workflowId := fs.String("workflow-id", "", "workflow id, e.g. order-123456")
+	runId := fs.String("run-id", "", "run id, the latest run if empty")
+	dir := fs.String("dir", filepath.Join("workflows", "testdata", "histories"), "directory to write the history to")
+	name := fs.String("name", "", "file name, <workflow-id>.json if empty")
+	fs.Parse(args)
+	if *workflowId == "" {
+		return fmt.Errorf("-workflow-id is required")
+	}
+	if *name == "" {
+		*name = *workflowId + ".json"
+	}
+
+	co, err := envconfig.LoadDefaultClientOptions()
+	if err != nil {
+		return err
+	}
+	co.Logger = newLogger()
+	c, err := client.Dial(co)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	history := &historypb.History{}
+	iter := c.GetWorkflowHistory(context.Background(), *workflowId, *runId, false, enums.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	for iter.HasNext() {
+		event, err := iter.Next()
+		if err != nil {
+			return err
+		}
+		history.Events = append(history.Events, event)
+	}
+
+	data, err := temporalproto.CustomJSONMarshalOptions{Indent: "  "}.Marshal(history)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(*dir, 0o755)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(*dir, *name)
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Wrote %v events to %v\n", len(history.Events), path)
+	return nil
+}
+
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	dir := fs.String("dir", filepath.Join("workflows", "testdata", "histories"), "directory with JSON histories")
+	// default to the patch setting of the worker
+	patchDefault, err := strconv.ParseBool(app.GetEnv("PATCH_RECOVERABLE_FAILURE", "false"))
+	if err != nil {
+		return fmt.Errorf("invalid PATCH_RECOVERABLE_FAILURE: %w", err)
+	}
+	patch := fs.Bool("patch-recoverable-failure", patchDefault, "replay with the RecoverableFailure fix, as patched workers run")
+	fs.Parse(args)
+	workflows.PatchRecoverableFailure = *patch
+
+	results, err := workflows.ReplayHistories(*dir, newLogger())
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Printf("FAIL  %v\n      %v\n", result.File, result.Err)
+		} else {
+			fmt.Printf("ok    %v\n", result.File)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%v of %v histories failed to replay", failed, len(results))
+	}
+	fmt.Printf("%v histories replayed\n", len(results))
+	return nil
+}
+
+// newLogger only logs warnings and errors so the command output stays readable.
+func newLogger() tlog.Logger {
+	return tlog.NewStructuredLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	})))
+}
@@ -0,0 +1,454 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"temporal-order-management/app"
+	"temporal-order-management/messages"
+	"temporal-order-management/workflows"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+)
+
+// lineItems collects repeated -item SKU:QTY flags.
+type lineItems []app.LineItem
+
+func (l *lineItems) String() string {
+	var items []string
+	for _, item := range *l {
+		items = append(items, fmt.Sprintf("%v:%v", item.Sku, item.Quantity))
+	}
+	return strings.Join(items, ",")
+}
+
+func (l *lineItems) Set(value string) error {
+	sku, quantity, found := strings.Cut(value, ":")
+	if !found {
+		quantity = "1"
+	}
+	q, err := strconv.Atoi(quantity)
+	if err != nil {
+		return fmt.Errorf("invalid quantity in %v", value)
+	}
+	*l = append(*l, app.LineItem{Sku: sku, Quantity: q})
+	return nil
+}
+
+func runScenarios(args []string) error {
+	fs, output := newFlagSet("scenarios")
+	fs.Parse(args)
+	p, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	scenarios := workflows.ListScenarios()
+	var rows [][]string
+	for _, scenario := range scenarios {
+		rows = append(rows, []string{scenario.Name, "OrderWorkflow" + scenario.Name, scenario.Description})
+	}
+	return p.printTable(scenarios, []string{"SCENARIO", "WORKFLOW TYPE", "DESCRIPTION"}, rows)
+}
+
+func runStart(args []string) error {
+	fs, output := newFlagSet("start")
+	scenario := fs.String("scenario", "HappyPath", "scenario to run, see ordersctl scenarios")
+	orderId := fs.String("order-id", "", "order id, random if empty")
+	address := fs.String("address", "123 Main St. Redwood, CA", "shipping address")
+	customer := fs.String("customer", "Alice Jones", "customer name")
+	paymentRef := fs.String("payment-ref", "", "payment reference")
+	taskQueue := fs.String("task-queue", app.GetEnv("TEMPORAL_TASK_QUEUE", "orders"), "task queue of the orders worker")
+	shippingTaskQueue := fs.String("shipping-task-queue", app.GetEnv("SHIPPING_TASK_QUEUE", ""), "task queue the order ships on, the orders task queue if empty")
+	watch := fs.Bool("watch", false, "watch the order until it finishes")
+	faultsFile := fs.String("faults", "", "YAML file of fault rules to inject into the order's activities")
+	var items lineItems
+	fs.Var(&items, "item", "line item as SKU:QTY, repeatable; the default table order if omitted")
+	fs.Parse(args)
+	p, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	workflowType, ok := workflows.ScenarioWorkflowType(*scenario)
+	if !ok {
+		return fmt.Errorf("unknown scenario %v", *scenario)
+	}
+	if *orderId == "" {
+		*orderId = fmt.Sprintf("%06d", rand.Intn(1000000))
+	}
+	var faults app.FaultRules
+	if *faultsFile != "" {
+		faults, err = app.LoadFaultRulesFile(*faultsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	c, err := dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	scenarioDef, _ := workflows.LookupScenario(workflowType)
+	input := app.OrderInput{
+		OrderId:           *orderId,
+		Address:           *address,
+		Customer:          app.Customer{Name: *customer},
+		Items:             items,
+		PaymentRef:        *paymentRef,
+		Scenario:          &scenarioDef,
+		ShippingTaskQueue: *shippingTaskQueue,
+	}
+	options := client.StartWorkflowOptions{
+		ID:        app.OrderWorkflowId(*orderId),
+		TaskQueue: *taskQueue,
+	}
+	if len(faults) > 0 {
+		options.Memo = map[string]interface{}{app.FaultsKey: faults}
+	}
+	ctx := context.Background()
+	run, err := c.ExecuteWorkflow(ctx, options, workflowType, input)
+	if err != nil {
+		return err
+	}
+
+	started := map[string]string{"orderId": *orderId, "workflowId": run.GetID(), "runId": run.GetRunID(), "workflowType": workflowType}
+	err = p.printTable(started, []string{"ORDER", "WORKFLOW ID", "RUN ID", "TYPE"},
+		[][]string{{*orderId, run.GetID(), run.GetRunID(), workflowType}})
+	if err != nil || !*watch {
+		return err
+	}
+	return watchOrder(ctx, c, p, *orderId)
+}
+
+func runWatch(args []string) error {
+	fs, output := newFlagSet("watch")
+	fs.Parse(args)
+	orderId, err := orderIdArg(fs)
+	if err != nil {
+		return err
+	}
+	p, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	c, err := dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	return watchOrder(context.Background(), c, p, orderId)
+}
+
+type progressLine struct {
+	Progress int    `json:"progress"`
+	Step     string `json:"step"`
+}
+
+// watchOrder prints the progress of an order whenever it changes, then its status.
+func watchOrder(ctx context.Context, c client.Client, p *printer, orderId string) error {
+	workflowId := app.OrderWorkflowId(orderId)
+	last := progressLine{Progress: -1}
+	for {
+		desc, err := c.DescribeWorkflowExecution(ctx, workflowId, "")
+		if err != nil {
+			return err
+		}
+		running := desc.GetWorkflowExecutionInfo().GetStatus() == enums.WORKFLOW_EXECUTION_STATUS_RUNNING
+		// an order entity runs until its return window closes
+		entity := desc.GetWorkflowExecutionInfo().GetType().GetName() == "OrderEntityWorkflow"
+
+		var line progressLine
+		value, err := c.QueryWorkflow(ctx, workflowId, "", "getOrderStatus")
+		if err == nil {
+			var status messages.OrderStatus
+			err = value.Get(&status)
+			line = progressLine{Progress: status.Progress, Step: status.CurrentStep}
+		} else {
+			// other SDKs only have getProgress
+			value, err = c.QueryWorkflow(ctx, workflowId, "", "getProgress")
+			if err == nil {
+				err = value.Get(&line.Progress)
+			}
+		}
+		if err != nil && running {
+			return err
+		}
+
+		if line != last {
+			if p.json() {
+				p.printJSON(line)
+			} else {
+				fmt.Printf("%3d%%  %v\n", line.Progress, line.Step)
+			}
+			last = line
+		}
+
+		if !running || entity {
+			return printStatus(ctx, c, p, orderId)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func runStatus(args []string) error {
+	fs, output := newFlagSet("status")
+	fs.Parse(args)
+	orderId, err := orderIdArg(fs)
+	if err != nil {
+		return err
+	}
+	p, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	c, err := dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	return printStatus(context.Background(), c, p, orderId)
+}
+
+type orderStatus struct {
+	OrderId     string                `json:"orderId"`
+	Status      string                `json:"status"`
+	OrderStatus *messages.OrderStatus `json:"orderStatus,omitempty"`
+	Result      *app.OrderOutput      `json:"result,omitempty"`
+	Error       string                `json:"error,omitempty"`
+}
+
+func printStatus(ctx context.Context, c client.Client, p *printer, orderId string) error {
+	workflowId := app.OrderWorkflowId(orderId)
+	desc, err := c.DescribeWorkflowExecution(ctx, workflowId, "")
+	if err != nil {
+		return err
+	}
+	status := desc.GetWorkflowExecutionInfo().GetStatus()
+	result := orderStatus{OrderId: orderId, Status: status.String()}
+
+	value, err := c.QueryWorkflow(ctx, workflowId, "", "getOrderStatus")
+	if err == nil {
+		var orderStatus messages.OrderStatus
+		if value.Get(&orderStatus) == nil {
+			result.OrderStatus = &orderStatus
+		}
+	}
+	if status != enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		var output app.OrderOutput
+		err = c.GetWorkflow(ctx, workflowId, "").Get(ctx, &output)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Result = &output
+		}
+	}
+
+	if p.json() {
+		return p.printJSON(result)
+	}
+
+	fmt.Printf("Order:     %v\n", orderId)
+	fmt.Printf("Workflow:  %v\n", result.Status)
+	if s := result.OrderStatus; s != nil {
+		fmt.Printf("Progress:  %v%%\n", s.Progress)
+		fmt.Printf("Step:      %v\n", s.CurrentStep)
+		for _, step := range s.CompletedSteps {
+			fmt.Printf("  done     %v at %v\n", step.Name, step.CompletedAt.Format(time.RFC3339))
+		}
+		if len(s.TrackingIds) > 0 {
+			fmt.Printf("Tracking:  %v\n", strings.Join(s.TrackingIds, ", "))
+		}
+		for _, compensation := range s.Compensations {
+			fmt.Printf("  undone   %v %v\n", compensation.Activity, compensation.Error)
+		}
+		if s.LastError != "" {
+			fmt.Printf("Error:     %v\n", s.LastError)
+		}
+		fmt.Println()
+		var rows [][]string
+		for _, item := range s.Items {
+			rows = append(rows, []string{strconv.Itoa(item.Id), item.Sku, item.Description, strconv.Itoa(item.Quantity), item.Status})
+		}
+		p.printTable(nil, []string{"ITEM", "SKU", "DESCRIPTION", "QTY", "STATUS"}, rows)
+	}
+	if result.Result != nil {
+		fmt.Printf("\nResult:    %v, tracking id %v, address %v\n", result.Result.Status, result.Result.TrackingId, result.Result.Address)
+	}
+	if result.Error != "" && (result.OrderStatus == nil || result.OrderStatus.LastError == "") {
+		fmt.Printf("\nError:     %v\n", result.Error)
+	}
+	return nil
+}
+
+func runUpdateAddress(args []string) error {
+	fs, output := newFlagSet("update-address")
+	signal := fs.Bool("signal", false, "send a signal instead of an update")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errors.New("usage: ordersctl update-address [-signal] <orderId> <address>")
+	}
+	input := messages.UpdateOrderInput{Address: fs.Arg(1)}
+	return sendMessage(*output, fs.Arg(0), "UpdateOrder", input, *signal)
+}
+
+func runCancel(args []string) error {
+	fs, output := newFlagSet("cancel")
+	reason := fs.String("reason", "cancelled by customer", "cancellation reason")
+	signal := fs.Bool("signal", false, "send a signal instead of an update")
+	fs.Parse(args)
+	orderId, err := orderIdArg(fs)
+	if err != nil {
+		return err
+	}
+	input := messages.CancelOrderInput{Reason: *reason}
+	return sendMessage(*output, orderId, "CancelOrder", input, *signal)
+}
+
+func runNote(args []string) error {
+	fs, output := newFlagSet("note")
+	author := fs.String("author", "", "author of the note")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errors.New("usage: ordersctl note [-author name] <orderId> <text>")
+	}
+	input := messages.AddNoteInput{Author: *author, Text: fs.Arg(1)}
+	return sendMessage(*output, fs.Arg(0), "AddNote", input, false)
+}
+
+func runDeliveryEvent(args []string) error {
+	fs, output := newFlagSet("delivery-event")
+	event := fs.String("event", messages.DeliveryEventDelivered, "outForDelivery, delivered or exception")
+	itemId := fs.Int("item", 0, "item the event is for, every item if zero")
+	detail := fs.String("detail", "", "detail from the carrier")
+	fs.Parse(args)
+	orderId, err := orderIdArg(fs)
+	if err != nil {
+		return err
+	}
+	input := messages.DeliveryEventInput{ItemId: *itemId, Event: *event, Detail: *detail}
+	return sendMessage(*output, orderId, "DeliveryEvent", input, true)
+}
+
+// sendMessage sends name as a signal or executes it as an update.
+func sendMessage(format string, orderId string, name string, input any, signal bool) error {
+	p, err := newPrinter(format)
+	if err != nil {
+		return err
+	}
+	c, err := dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	workflowId := app.OrderWorkflowId(orderId)
+	result := name + " signal sent"
+	if signal {
+		err = c.SignalWorkflow(ctx, workflowId, "", name, input)
+	} else {
+		var handle client.WorkflowUpdateHandle
+		handle, err = c.UpdateWorkflow(ctx, client.UpdateWorkflowOptions{
+			WorkflowID:   workflowId,
+			UpdateName:   name,
+			Args:         []any{input},
+			WaitForStage: client.WorkflowUpdateStageCompleted,
+		})
+		if err == nil {
+			err = handle.Get(ctx, &result)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return p.printTable(map[string]string{"orderId": orderId, "result": result},
+		[]string{"ORDER", "RESULT"}, [][]string{{orderId, result}})
+}
+
+type listedOrder struct {
+	WorkflowId   string    `json:"workflowId"`
+	WorkflowType string    `json:"workflowType"`
+	Status       string    `json:"status"`
+	OrderStatus  string    `json:"orderStatus"`
+	StartTime    time.Time `json:"startTime"`
+}
+
+func runList(args []string) error {
+	fs, output := newFlagSet("list")
+	orderStatus := fs.String("status", "", "only orders with this OrderStatus, e.g. \"Ship Order\"")
+	limit := fs.Int("limit", 20, "maximum number of orders")
+	fs.Parse(args)
+	p, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	c, err := dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	query := "WorkflowId STARTS_WITH 'order-'"
+	if *orderStatus != "" {
+		query = fmt.Sprintf("OrderStatus = '%v'", strings.ReplaceAll(*orderStatus, "'", "\\'"))
+	}
+
+	orders := []listedOrder{}
+	var token []byte
+	for len(orders) < *limit {
+		resp, err := c.ListWorkflow(context.Background(), &workflowservice.ListWorkflowExecutionsRequest{
+			Query:         query,
+			PageSize:      int32(*limit - len(orders)),
+			NextPageToken: token,
+		})
+		if err != nil {
+			return err
+		}
+		for _, exec := range resp.GetExecutions() {
+			order := listedOrder{
+				WorkflowId:   exec.GetExecution().GetWorkflowId(),
+				WorkflowType: exec.GetType().GetName(),
+				Status:       exec.GetStatus().String(),
+				StartTime:    exec.GetStartTime().AsTime(),
+			}
+			if payload, ok := exec.GetSearchAttributes().GetIndexedFields()["OrderStatus"]; ok {
+				converter.GetDefaultDataConverter().FromPayload(payload, &order.OrderStatus)
+			}
+			orders = append(orders, order)
+		}
+		token = resp.GetNextPageToken()
+		if len(token) == 0 {
+			break
+		}
+	}
+
+	var rows [][]string
+	for _, order := range orders {
+		rows = append(rows, []string{order.WorkflowId, order.WorkflowType, order.Status, order.OrderStatus, order.StartTime.Local().Format(time.DateTime)})
+	}
+	return p.printTable(orders, []string{"WORKFLOW ID", "TYPE", "STATUS", "ORDER STATUS", "STARTED"}, rows)
+}
+
+func orderIdArg(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() != 1 {
+		return "", fmt.Errorf("usage: ordersctl %v [flags] <orderId>", fs.Name())
+	}
+	return fs.Arg(0), nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"temporal-order-management/app"
+	"temporal-order-management/workflows"
+
+	"go.temporal.io/sdk/client"
+)
+
+const usage = `Usage: ordersctl <command> [flags] [args]
+
+Commands:
+  scenarios                        list the demo scenarios
+  start [flags]                    start an order
+  watch <orderId>                  follow the progress of an order until it finishes
+  status <orderId>                 show the getOrderStatus query and the result of an order
+  update-address <orderId> <addr>  send UpdateOrder as an update, or a signal with -signal
+  cancel <orderId>                 cancel an order with the CancelOrder update
+  list                             list orders, optionally by their OrderStatus search attribute
+  note <orderId> <text>            add a customer note to an order entity with the AddNote update
+  delivery-event <orderId>         send a DeliveryEvent signal to an order entity
+  return <orderId> <itemId>        start the return of an item of a completed order or order entity
+  receive-return <orderId> <itemId>
+                                   send ReturnReceived when the returned item arrives
+  return-status <orderId> <itemId> show the getReturnStatus query and the result of a return
+
+Every command accepts -o table|json. Run "ordersctl <command> -h" for its flags.
+`
+
+type command func(args []string) error
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]command{
+		"scenarios":      runScenarios,
+		"start":          runStart,
+		"watch":          runWatch,
+		"status":         runStatus,
+		"update-address": runUpdateAddress,
+		"cancel":         runCancel,
+		"list":           runList,
+		"note":           runNote,
+		"delivery-event": runDeliveryEvent,
+		"return":         runReturn,
+		"receive-return": runReceiveReturn,
+		"return-status":  runReturnStatus,
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	err := workflows.LoadScenarios()
+	if err == nil {
+		err = cmd(os.Args[2:])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// newFlagSet returns the flag set for a command with the shared -o flag.
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	output := fs.String("o", "table", "output format, table or json")
+	return fs, output
+}
+
+// dial connects with the same client options as the workers, including the
+// credentials and tracing, without serving metrics or the API key rotation
+// endpoint. SDK warnings are logged to stderr to keep stdout clean for output.
+func dial() (client.Client, error) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	}))
+	co, _, err := app.LoadClientOptions(logger, nil)
+	if err != nil {
+		return nil, err
+	}
+	shutdownTracing := app.ConfigureTracing(&co, "ordersctl")
+	c, err := client.Dial(co)
+	if err != nil {
+		shutdownTracing()
+		return nil, err
+	}
+	return tracedClient{Client: c, shutdownTracing: shutdownTracing}, nil
+}
+
+// tracedClient flushes the spans of the command when it is closed.
+type tracedClient struct {
+	client.Client
+	shutdownTracing func()
+}
+
+func (c tracedClient) Close() {
+	c.Client.Close()
+	c.shutdownTracing()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer writes either JSON or a table to stdout.
+type printer struct {
+	format string
+}
+
+func newPrinter(format string) (*printer, error) {
+	if format != "table" && format != "json" {
+		return nil, fmt.Errorf("unknown output format %v", format)
+	}
+	return &printer{format: format}, nil
+}
+
+func (p *printer) json() bool {
+	return p.format == "json"
+}
+
+func (p *printer) printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printTable prints rows under headers, or v as JSON in json mode.
+func (p *printer) printTable(v any, headers []string, rows [][]string) error {
+	if p.json() {
+		return p.printJSON(v)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"temporal-order-management/app"
+	"temporal-order-management/messages"
+	"temporal-order-management/workflows"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/client"
+)
+
+func runReturn(args []string) error {
+	fs, output := newFlagSet("return")
+	reason := fs.String("reason", "no longer needed", "return reason")
+	taskQueue := fs.String("task-queue", app.GetEnv("TEMPORAL_TASK_QUEUE", "orders"), "task queue of the orders worker")
+	fs.Parse(args)
+	orderId, itemId, err := returnArgs(fs.Name(), fs.Args())
+	if err != nil {
+		return err
+	}
+	p, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	c, err := dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	workflowId := app.OrderWorkflowId(orderId)
+	desc, err := c.DescribeWorkflowExecution(ctx, workflowId, "")
+	if err != nil {
+		return err
+	}
+	switch desc.GetWorkflowExecutionInfo().GetStatus() {
+	case enums.WORKFLOW_EXECUTION_STATUS_COMPLETED:
+	case enums.WORKFLOW_EXECUTION_STATUS_RUNNING:
+		// an order entity starts its own returns
+		input := messages.RequestReturnInput{ItemId: itemId, Reason: *reason}
+		return sendMessage(*output, orderId, "RequestReturn", input, false)
+	default:
+		return fmt.Errorf("order %v is not completed", orderId)
+	}
+	var order app.OrderOutput
+	err = c.GetWorkflow(ctx, workflowId, "").Get(ctx, &order)
+	if err != nil {
+		return err
+	}
+	input, err := app.NewReturnInput(orderId, order, itemId, *reason)
+	if err != nil {
+		return err
+	}
+
+	options := client.StartWorkflowOptions{
+		ID:        app.ReturnWorkflowId(orderId, itemId),
+		TaskQueue: *taskQueue,
+	}
+	run, err := c.ExecuteWorkflow(ctx, options, workflows.ReturnWorkflow, input)
+	if err != nil {
+		return err
+	}
+
+	started := map[string]any{"orderId": orderId, "itemId": itemId, "workflowId": run.GetID(), "runId": run.GetRunID()}
+	return p.printTable(started, []string{"ORDER", "ITEM", "WORKFLOW ID", "RUN ID"},
+		[][]string{{orderId, strconv.Itoa(itemId), run.GetID(), run.GetRunID()}})
+}
+
+func runReceiveReturn(args []string) error {
+	fs, output := newFlagSet("receive-return")
+	condition := fs.String("condition", app.ReturnConditionUnopened, "condition of the item: unopened, opened or damaged")
+	notes := fs.String("notes", "", "notes from the warehouse")
+	fs.Parse(args)
+	orderId, itemId, err := returnArgs(fs.Name(), fs.Args())
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(app.ReturnConditions, *condition) {
+		return fmt.Errorf("unknown condition %q, expected one of %v", *condition, app.ReturnConditions)
+	}
+	p, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	c, err := dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	input := messages.ReturnReceivedInput{Condition: *condition, Notes: *notes}
+	err = c.SignalWorkflow(context.Background(), app.ReturnWorkflowId(orderId, itemId), "", "ReturnReceived", input)
+	if err != nil {
+		return err
+	}
+	result := "ReturnReceived signal sent"
+	return p.printTable(map[string]string{"result": result}, []string{"RESULT"}, [][]string{{result}})
+}
+
+type returnStatus struct {
+	OrderId      string                 `json:"orderId"`
+	ItemId       int                    `json:"itemId"`
+	Status       string                 `json:"status"`
+	ReturnStatus *messages.ReturnStatus `json:"returnStatus,omitempty"`
+	Result       *app.ReturnOutput      `json:"result,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+func runReturnStatus(args []string) error {
+	fs, output := newFlagSet("return-status")
+	fs.Parse(args)
+	orderId, itemId, err := returnArgs(fs.Name(), fs.Args())
+	if err != nil {
+		return err
+	}
+	p, err := newPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	c, err := dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	workflowId := app.ReturnWorkflowId(orderId, itemId)
+	desc, err := c.DescribeWorkflowExecution(ctx, workflowId, "")
+	if err != nil {
+		return err
+	}
+	status := desc.GetWorkflowExecutionInfo().GetStatus()
+	result := returnStatus{OrderId: orderId, ItemId: itemId, Status: status.String()}
+
+	value, err := c.QueryWorkflow(ctx, workflowId, "", "getReturnStatus")
+	if err == nil {
+		var returnStatus messages.ReturnStatus
+		if value.Get(&returnStatus) == nil {
+			result.ReturnStatus = &returnStatus
+		}
+	}
+	if status != enums.WORKFLOW_EXECUTION_STATUS_RUNNING {
+		var output app.ReturnOutput
+		err = c.GetWorkflow(ctx, workflowId, "").Get(ctx, &output)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Result = &output
+		}
+	}
+
+	if p.json() {
+		return p.printJSON(result)
+	}
+
+	fmt.Printf("Order:     %v\n", orderId)
+	fmt.Printf("Item:      %v\n", itemId)
+	fmt.Printf("Workflow:  %v\n", result.Status)
+	if s := result.ReturnStatus; s != nil {
+		fmt.Printf("Return:    %v\n", s.Status)
+		if s.Condition != "" {
+			fmt.Printf("Condition: %v\n", s.Condition)
+		}
+		fmt.Printf("Refund:    %v\n", s.RefundAmount)
+		fmt.Printf("Restocked: %v\n", s.Restocked)
+		for _, compensation := range s.Compensations {
+			fmt.Printf("  undone   %v %v\n", compensation.Activity, compensation.Error)
+		}
+		if s.LastError != "" {
+			fmt.Printf("Error:     %v\n", s.LastError)
+		}
+	}
+	if result.Error != "" && (result.ReturnStatus == nil || result.ReturnStatus.LastError == "") {
+		fmt.Printf("Error:     %v\n", result.Error)
+	}
+	return nil
+}
+
+// returnArgs parses the <orderId> <itemId> arguments of the return commands.
+func returnArgs(name string, args []string) (string, int, error) {
+	if len(args) != 2 {
+		return "", 0, fmt.Errorf("usage: ordersctl %v [flags] <orderId> <itemId>", name)
+	}
+	itemId, err := strconv.Atoi(args[1])
+	if err != nil {
+		return "", 0, errors.New("itemId must be a number")
+	}
+	return args[0], itemId, nil
+}
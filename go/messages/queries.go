@@ -1,6 +1,8 @@
 package messages
 
 import (
+	"temporal-order-management/app"
+
 	"go.temporal.io/sdk/workflow"
 )
 
@@ -20,3 +22,47 @@ func SetQueryHandlerForProgress(ctx workflow.Context) (*int, error) {
 
 	return &progress, nil
 }
+
+// "getOrderStatus" query handler, progress is the value exposed by "getProgress"
+func SetQueryHandlerForOrderStatus(ctx workflow.Context, orderId string, progress *int) (*OrderStatus, error) {
+	logger := workflow.GetLogger(ctx)
+
+	status := &OrderStatus{OrderId: orderId}
+
+	err := workflow.SetQueryHandler(ctx, "getOrderStatus", func() (OrderStatus, error) {
+		result := *status
+		result.Progress = *progress
+		// the build of the worker that last moved the order on
+		result.BuildId = workflow.GetInfo(ctx).GetCurrentBuildID()
+		return result, nil
+	})
+	if err != nil {
+		logger.Error("SetQueryHandler failed for getOrderStatus: " + err.Error())
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// "getReturnStatus" query handler
+func SetQueryHandlerForReturnStatus(ctx workflow.Context, input app.ReturnInput) (*ReturnStatus, error) {
+	logger := workflow.GetLogger(ctx)
+
+	status := &ReturnStatus{
+		OrderId: input.Shipment.Order.OrderId,
+		ItemId:  input.Shipment.Item.Id,
+		Sku:     input.Shipment.Item.Sku,
+		Reason:  input.Reason,
+		Status:  ReturnStatusAwaitingItem,
+	}
+
+	err := workflow.SetQueryHandler(ctx, "getReturnStatus", func() (ReturnStatus, error) {
+		return *status, nil
+	})
+	if err != nil {
+		logger.Error("SetQueryHandler failed for getReturnStatus: " + err.Error())
+		return nil, err
+	}
+
+	return status, nil
+}
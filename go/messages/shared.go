@@ -1,5 +1,181 @@
 package messages
 
+import (
+	"temporal-order-management/app"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
 type UpdateOrderInput struct {
 	Address string `json:"address"`
 }
+
+type CancelOrderInput struct {
+	Reason string `json:"reason"`
+}
+
+const (
+	ItemStatusPending   = "pending"
+	ItemStatusShipping  = "shipping"
+	ItemStatusShipped   = "shipped"
+	ItemStatusDelivered = "delivered"
+	ItemStatusFailed    = "failed"
+)
+
+// DeliveryEventInput is sent by the carrier as a shipped order makes its way
+// to the customer.
+type DeliveryEventInput struct {
+	// ItemId is the item the event is for, every item of the order if zero
+	ItemId int    `json:"itemId,omitempty"`
+	Event  string `json:"event"`
+	Detail string `json:"detail,omitempty"`
+}
+
+const (
+	DeliveryEventOutForDelivery = "outForDelivery"
+	DeliveryEventDelivered      = "delivered"
+	DeliveryEventException      = "exception"
+)
+
+// AddNoteInput adds a customer note to an order.
+type AddNoteInput struct {
+	Author string `json:"author,omitempty"`
+	Text   string `json:"text"`
+}
+
+// RequestReturnInput asks an order entity to start the return of an item.
+type RequestReturnInput struct {
+	ItemId int    `json:"itemId"`
+	Reason string `json:"reason"`
+}
+
+// ReturnReceivedInput is sent when a returned item arrives at the warehouse.
+type ReturnReceivedInput struct {
+	// Condition is unopened, opened or damaged
+	Condition string `json:"condition"`
+	Notes     string `json:"notes,omitempty"`
+}
+
+const (
+	ReturnStatusAwaitingItem = "awaitingItem"
+	ReturnStatusInspecting   = "inspecting"
+	ReturnStatusRefunded     = "refunded"
+	ReturnStatusRejected     = "rejected"
+	ReturnStatusExpired      = "expired"
+	ReturnStatusFailed       = "failed"
+)
+
+// ReturnStatus is returned by the "getReturnStatus" query.
+type ReturnStatus struct {
+	OrderId       string                   `json:"orderId"`
+	ItemId        int                      `json:"itemId"`
+	Sku           string                   `json:"sku"`
+	Reason        string                   `json:"reason,omitempty"`
+	Status        string                   `json:"status"`
+	Condition     string                   `json:"condition,omitempty"`
+	RefundAmount  int64                    `json:"refundAmount"`
+	Restocked     bool                     `json:"restocked"`
+	Compensations []app.CompensationRecord `json:"compensations"`
+	LastError     string                   `json:"lastError,omitempty"`
+}
+
+// OrderStatus is returned by the "getOrderStatus" query.
+type OrderStatus struct {
+	OrderId        string                   `json:"orderId"`
+	Progress       int                      `json:"progress"`
+	CurrentStep    string                   `json:"currentStep"`
+	CompletedSteps []CompletedStep          `json:"completedSteps"`
+	Items          []ItemStatus             `json:"items"`
+	TrackingIds    []string                 `json:"trackingIds"`
+	Compensations  []app.CompensationRecord `json:"compensations"`
+	LastError      string                   `json:"lastError,omitempty"`
+	BuildId        string                   `json:"buildId,omitempty"`
+	// Set once the order is an entity, see OrderEntityWorkflow
+	Address        string          `json:"address,omitempty"`
+	DeliveryEvents []DeliveryEvent `json:"deliveryEvents,omitempty"`
+	Notes          []Note          `json:"notes,omitempty"`
+	Returns        []string        `json:"returns,omitempty"`
+	ContinuedAsNew int             `json:"continuedAsNew,omitempty"`
+}
+
+type DeliveryEvent struct {
+	ItemId     int       `json:"itemId,omitempty"`
+	Event      string    `json:"event"`
+	Detail     string    `json:"detail,omitempty"`
+	ReceivedAt time.Time `json:"receivedAt"`
+}
+
+type Note struct {
+	Author  string    `json:"author,omitempty"`
+	Text    string    `json:"text"`
+	AddedAt time.Time `json:"addedAt"`
+}
+
+type CompletedStep struct {
+	Name        string    `json:"name"`
+	CompletedAt time.Time `json:"completedAt"`
+}
+
+type ItemStatus struct {
+	Id          int    `json:"id"`
+	Sku         string `json:"sku"`
+	Description string `json:"description"`
+	Quantity    int    `json:"quantity"`
+	Status      string `json:"status"`
+}
+
+// StartStep completes the current step, if any, and makes step the current one.
+func (s *OrderStatus) StartStep(ctx workflow.Context, step string) {
+	if s.CurrentStep != "" && s.CurrentStep != step {
+		s.CompletedSteps = append(s.CompletedSteps, CompletedStep{Name: s.CurrentStep, CompletedAt: workflow.Now(ctx)})
+	}
+	s.CurrentStep = step
+}
+
+func (s *OrderStatus) SetItems(items app.Items) {
+	s.Items = make([]ItemStatus, len(items))
+	for i, item := range items {
+		s.Items[i] = ItemStatus{
+			Id:          item.Id,
+			Sku:         item.Sku,
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			Status:      ItemStatusPending,
+		}
+	}
+}
+
+// SetItemStatus sets the status of the item at index i of the order items.
+func (s *OrderStatus) SetItemStatus(i int, status string) {
+	s.Items[i].Status = status
+}
+
+// AddDeliveryEvent records a delivery event, marking the items it is for as
+// delivered once the carrier delivers them.
+func (s *OrderStatus) AddDeliveryEvent(ctx workflow.Context, event DeliveryEventInput) {
+	s.DeliveryEvents = append(s.DeliveryEvents, DeliveryEvent{
+		ItemId:     event.ItemId,
+		Event:      event.Event,
+		Detail:     event.Detail,
+		ReceivedAt: workflow.Now(ctx),
+	})
+	if event.Event != DeliveryEventDelivered {
+		return
+	}
+	for i, item := range s.Items {
+		if event.ItemId == 0 || event.ItemId == item.Id {
+			s.SetItemStatus(i, ItemStatusDelivered)
+		}
+	}
+}
+
+// Delivered reports whether every item of the order was delivered.
+func (s *OrderStatus) Delivered() bool {
+	for _, item := range s.Items {
+		if item.Status != ItemStatusDelivered {
+			return false
+		}
+	}
+	return len(s.Items) > 0
+}
@@ -6,3 +6,18 @@ import "go.temporal.io/sdk/workflow"
 func GetSignalChannelForUpdateOrder(ctx workflow.Context) workflow.ReceiveChannel {
 	return workflow.GetSignalChannel(ctx, "UpdateOrder")
 }
+
+// "CancelOrder" signal channel
+func GetSignalChannelForCancelOrder(ctx workflow.Context) workflow.ReceiveChannel {
+	return workflow.GetSignalChannel(ctx, "CancelOrder")
+}
+
+// "ReturnReceived" signal channel
+func GetSignalChannelForReturnReceived(ctx workflow.Context) workflow.ReceiveChannel {
+	return workflow.GetSignalChannel(ctx, "ReturnReceived")
+}
+
+// "DeliveryEvent" signal channel
+func GetSignalChannelForDeliveryEvent(ctx workflow.Context) workflow.ReceiveChannel {
+	return workflow.GetSignalChannel(ctx, "DeliveryEvent")
+}
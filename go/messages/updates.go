@@ -3,6 +3,7 @@ package messages
 import (
 	"errors"
 	"regexp"
+	"strings"
 
 	"go.temporal.io/sdk/workflow"
 )
@@ -44,3 +45,139 @@ func validateAddress(ctx workflow.Context, update UpdateOrderInput) error {
 	logger.Info("Updating order, address " + update.Address)
 	return nil
 }
+
+// "CancelOrder" update handler, canCancel reports whether the order can still be cancelled
+func SetUpdateHandlerForCancelOrder(ctx workflow.Context, canCancel func() bool, onCancel func(CancelOrderInput)) error {
+	logger := workflow.GetLogger(ctx)
+
+	err := workflow.SetUpdateHandlerWithOptions(
+		ctx,
+		"CancelOrder",
+		func(ctx workflow.Context, cancelInput CancelOrderInput) (string, error) {
+			onCancel(cancelInput)
+			return "Order cancelled", nil
+		},
+		workflow.UpdateHandlerOptions{Validator: validateCancel(canCancel)},
+	)
+
+	if err != nil {
+		logger.Error("SetUpdateHandler failed for CancelOrder: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func validateCancel(canCancel func() bool) func(workflow.Context, CancelOrderInput) error {
+	return func(ctx workflow.Context, cancelInput CancelOrderInput) error {
+		logger := workflow.GetLogger(ctx)
+
+		if !canCancel() {
+			msg := "Rejecting order cancellation, order is already shipping or cancelled"
+			logger.Info(msg)
+			return errors.New(msg)
+		}
+
+		logger.Info("Cancelling order, reason " + cancelInput.Reason)
+		return nil
+	}
+}
+
+// "UpdateOrder" update handler for orders that have shipped, canCorrect reports
+// whether the address can still be corrected
+func SetUpdateHandlerForAddressCorrection(ctx workflow.Context, canCorrect func() bool, onCorrect func(UpdateOrderInput)) error {
+	logger := workflow.GetLogger(ctx)
+
+	err := workflow.SetUpdateHandlerWithOptions(
+		ctx,
+		"UpdateOrder",
+		func(ctx workflow.Context, updateInput UpdateOrderInput) (string, error) {
+			onCorrect(updateInput)
+			return updateInput.Address, nil
+		},
+		workflow.UpdateHandlerOptions{Validator: validateAddressCorrection(canCorrect)},
+	)
+
+	if err != nil {
+		logger.Error("SetUpdateHandler failed for UpdateOrder: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func validateAddressCorrection(canCorrect func() bool) func(workflow.Context, UpdateOrderInput) error {
+	return func(ctx workflow.Context, update UpdateOrderInput) error {
+		if !canCorrect() {
+			msg := "Rejecting address correction, order is already delivered"
+			workflow.GetLogger(ctx).Info(msg)
+			return errors.New(msg)
+		}
+		return validateAddress(ctx, update)
+	}
+}
+
+// "AddNote" update handler
+func SetUpdateHandlerForAddNote(ctx workflow.Context, onNote func(workflow.Context, AddNoteInput)) error {
+	logger := workflow.GetLogger(ctx)
+
+	err := workflow.SetUpdateHandlerWithOptions(
+		ctx,
+		"AddNote",
+		func(ctx workflow.Context, noteInput AddNoteInput) (string, error) {
+			onNote(ctx, noteInput)
+			return "Note added", nil
+		},
+		workflow.UpdateHandlerOptions{Validator: validateNote},
+	)
+
+	if err != nil {
+		logger.Error("SetUpdateHandler failed for AddNote: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func validateNote(ctx workflow.Context, noteInput AddNoteInput) error {
+	if strings.TrimSpace(noteInput.Text) == "" {
+		msg := "Rejecting note, text is empty"
+		workflow.GetLogger(ctx).Info(msg)
+		return errors.New(msg)
+	}
+	return nil
+}
+
+// "RequestReturn" update handler, canReturn returns why an item cannot be
+// returned, and onReturn starts the return and returns its workflow id
+func SetUpdateHandlerForRequestReturn(ctx workflow.Context, canReturn func(RequestReturnInput) error, onReturn func(workflow.Context, RequestReturnInput) (string, error)) error {
+	logger := workflow.GetLogger(ctx)
+
+	err := workflow.SetUpdateHandlerWithOptions(
+		ctx,
+		"RequestReturn",
+		onReturn,
+		workflow.UpdateHandlerOptions{Validator: validateReturn(canReturn)},
+	)
+
+	if err != nil {
+		logger.Error("SetUpdateHandler failed for RequestReturn: " + err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func validateReturn(canReturn func(RequestReturnInput) error) func(workflow.Context, RequestReturnInput) error {
+	return func(ctx workflow.Context, returnInput RequestReturnInput) error {
+		logger := workflow.GetLogger(ctx)
+
+		if err := canReturn(returnInput); err != nil {
+			logger.Info("Rejecting return, " + err.Error())
+			return err
+		}
+
+		logger.Info("Returning item", "itemId", returnInput.ItemId, "reason", returnInput.Reason)
+		return nil
+	}
+}
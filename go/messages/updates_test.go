@@ -0,0 +1,63 @@
+package messages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+// updateOrderWorkflow returns the address set by the UpdateOrder update, or an
+// empty string if none arrives within a minute.
+func updateOrderWorkflow(ctx workflow.Context) (string, error) {
+	updatedAddress, err := SetUpdateHandlerForUpdateOrder(ctx)
+	if err != nil {
+		return "", err
+	}
+	workflow.AwaitWithTimeout(ctx, time.Minute, func() bool {
+		return *updatedAddress != ""
+	})
+	return *updatedAddress, nil
+}
+
+func TestValidateAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		valid   bool
+	}{
+		{"123 Main St. Redwood, CA", true},
+		{"1", true},
+		{"Main St. 123", false},
+		{"", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.address, func(t *testing.T) {
+			var s testsuite.WorkflowTestSuite
+			env := s.NewTestWorkflowEnvironment()
+			var rejectErr error
+			env.RegisterDelayedCallback(func() {
+				env.UpdateWorkflow("UpdateOrder", "update-1", &testsuite.TestUpdateCallback{
+					OnAccept:   func() {},
+					OnReject:   func(err error) { rejectErr = err },
+					OnComplete: func(any, error) {},
+				}, UpdateOrderInput{Address: test.address})
+			}, time.Second)
+
+			env.ExecuteWorkflow(updateOrderWorkflow)
+
+			require.NoError(t, env.GetWorkflowError())
+			var address string
+			require.NoError(t, env.GetWorkflowResult(&address))
+			if test.valid {
+				require.NoError(t, rejectErr)
+				require.Equal(t, test.address, address)
+			} else {
+				require.ErrorContains(t, rejectErr, "invalid address")
+				require.Empty(t, address)
+			}
+		})
+	}
+}
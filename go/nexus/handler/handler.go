@@ -16,6 +16,9 @@ var ShippingOperation = temporalnexus.NewWorkflowRunOperation(
 	app.ShippingOperationName,
 	workflows.ShippingWorkflow,
 	func(ctx context.Context, input app.ShippingInput, soo nexus.StartOperationOptions) (client.StartWorkflowOptions, error) {
+		if input.Shipment != nil {
+			return client.StartWorkflowOptions{ID: app.ShipmentWorkflowId(input.Order.OrderId, input.Shipment.Id)}, nil
+		}
 		return client.StartWorkflowOptions{ID: fmt.Sprintf("shipment-%v-%v", input.Order.OrderId, input.Item.Id)}, nil
 	},
 )
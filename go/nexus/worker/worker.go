@@ -2,9 +2,12 @@ package main
 
 import (
 	"log"
+	"log/slog"
+	"time"
 
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/contrib/envconfig"
+	"go.temporal.io/sdk/interceptor"
 	"go.temporal.io/sdk/worker"
 
 	"temporal-order-management/activities"
@@ -28,6 +31,14 @@ func main() {
 	if err != nil {
 		log.Fatalln("error loading default client options", err)
 	}
+	co.DataConverter = app.GetDataConverter()
+	co.MetricsHandler = app.NewMetricsHandler(app.GetEnv("TEMPORAL_NEXUS_METRICS_ADDRESS", "0.0.0.0:9091"))
+	_, err = app.ConfigureCredentials(&co, "TEMPORAL_NEXUS_", slog.Default())
+	if err != nil {
+		log.Fatalln("Unable to configure credentials", err)
+	}
+	shutdownTracing := app.ConfigureTracing(&co, "shipping-worker")
+	defer shutdownTracing()
 
 	c, err := client.Dial(co)
 	if err != nil {
@@ -36,7 +47,23 @@ func main() {
 	log.Printf("✅ Client connected to %v in namespace '%v'", co.HostPort, co.Namespace)
 	defer c.Close()
 
-	w := worker.New(c, app.GetEnv("TEMPORAL_NEXUS_TASK_QUEUE", "shipping"), worker.Options{})
+	// Nexus operations don't carry the memo of the order, so the shipping
+	// workflows they start only see the rules in TEMPORAL_NEXUS_FAULTS_FILE
+	var faults app.FaultRules
+	faultsFile := app.GetEnv("TEMPORAL_NEXUS_FAULTS_FILE", "")
+	if faultsFile != "" {
+		faults, err = app.LoadFaultRulesFile(faultsFile)
+		if err != nil {
+			log.Fatalln("Unable to load fault rules", err)
+		}
+		log.Printf("Injecting faults with %v rules", len(faults))
+	}
+
+	w := worker.New(c, app.GetEnv("TEMPORAL_NEXUS_TASK_QUEUE", "shipping"), worker.Options{
+		// time given to in-flight activities when the worker is drained
+		WorkerStopTimeout: 30 * time.Second,
+		Interceptors:      []interceptor.WorkerInterceptor{app.NewFaultInjector(faults, co.DataConverter)},
+	})
 	service := nexus.NewService(app.ShippingServiceName)
 	err = service.Register(handler.ShippingOperation)
 	if err != nil {
@@ -46,10 +73,21 @@ func main() {
 	w.RegisterWorkflow(workflows.ShippingWorkflow)
 	w.RegisterActivity(activities.ShipOrder)
 
-	err = w.Run(worker.InterruptCh())
+	admin := app.NewAdminServer(c, w, app.GetEnv("ADMIN_DRAIN_SECRET", ""))
+	admin.Start(app.GetEnv("TEMPORAL_NEXUS_ADMIN_ADDRESS", "0.0.0.0:8091"))
+
+	err = w.Start()
 	if err != nil {
 		log.Fatalln("Unable to start worker", err)
 	}
+	admin.SetStarted()
+
+	// Stop on interrupt, or once drained through the admin server
+	select {
+	case <-worker.InterruptCh():
+	case <-admin.Drained():
+	}
+	admin.Drain()
 }
 
 type EnvLookupMap map[string]string
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"temporal-order-management/activities"
+	"temporal-order-management/app"
+)
+
+// A local payment gateway for PAYMENT_GATEWAY_URL, backed by the in-memory gateway.
+func main() {
+	address := app.GetEnv("PAYMENT_STUB_ADDRESS", "localhost:8081")
+	handler := activities.NewPaymentStubHandler(activities.NewMemoryPaymentGateway())
+
+	log.Printf("✅ Payment stub listening on %v", address)
+	err := http.ListenAndServe(address, handler)
+	if err != nil {
+		log.Fatalln("Unable to start payment stub", err)
+	}
+}
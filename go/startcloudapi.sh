@@ -0,0 +1,3 @@
+#!/bin/bash
+source ../setcloudenv.sh
+go run ./cmd/api
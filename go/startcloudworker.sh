@@ -1,3 +1,3 @@
 #!/bin/bash
 source ../setcloudenv.sh
-go run worker/main.go
\ No newline at end of file
+go run ./worker
\ No newline at end of file
@@ -0,0 +1,4 @@
+#!/bin/bash
+export TEMPORAL_ADDRESS=localhost:7233
+export TEMPORAL_NAMESPACE=default
+go run ./cmd/api
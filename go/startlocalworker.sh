@@ -1,4 +1,4 @@
 #!/bin/bash
 export TEMPORAL_ADDRESS=localhost:7233
 export TEMPORAL_NAMESPACE=default
-go run worker/main.go
\ No newline at end of file
+go run ./worker
\ No newline at end of file
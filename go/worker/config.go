@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"temporal-order-management/activities"
+	"temporal-order-management/app"
+	"temporal-order-management/nexus/handler"
+	"temporal-order-management/workflows"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nexus-rpc/sdk-go/nexus"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the worker configuration file, in YAML or TOML. Each entry in
+// Workers runs a worker on its own task queue in this process, so the same
+// binary can run as an orders worker, a shipping-only worker or a combined dev
+// worker. Connection settings still come from envconfig.
+type Config struct {
+	// StickyCacheSize is the number of workflows cached across all workers,
+	// the SDK default is used if zero.
+	StickyCacheSize int `yaml:"stickyCacheSize" toml:"stickyCacheSize"`
+	// OrderWorkflowVersion is the version of the order workflow to run, 1 or
+	// 2, see the VersionPinned and VersionAutoUpgrade scenarios.
+	OrderWorkflowVersion int `yaml:"orderWorkflowVersion" toml:"orderWorkflowVersion"`
+	// PatchRecoverableFailure enables the fix for the simulated bug of the
+	// RecoverableFailure scenario.
+	PatchRecoverableFailure bool             `yaml:"patchRecoverableFailure" toml:"patchRecoverableFailure"`
+	Deployment              DeploymentConfig `yaml:"deployment" toml:"deployment"`
+	// Faults are injected into the activities of every order, before any
+	// rules in the memo of an order
+	Faults  app.FaultRules `yaml:"faults" toml:"faults"`
+	Workers []WorkerConfig `yaml:"workers" toml:"workers"`
+}
+
+// DeploymentConfig opts every worker into Worker Deployment versioning when
+// Name is set. Each build of the workers should have its own BuildId.
+type DeploymentConfig struct {
+	Name    string `yaml:"name" toml:"name"`
+	BuildId string `yaml:"buildId" toml:"buildId"`
+	// DefaultVersioningBehavior is "pinned", the default, or "auto-upgrade"
+	// and applies to workflows without a behavior of their own.
+	DefaultVersioningBehavior string `yaml:"defaultVersioningBehavior" toml:"defaultVersioningBehavior"`
+}
+
+// WorkerConfig configures a worker on a single task queue. Zero values use the
+// SDK defaults.
+type WorkerConfig struct {
+	TaskQueue string `yaml:"taskQueue" toml:"taskQueue"`
+
+	MaxConcurrentActivityExecutionSize      int     `yaml:"maxConcurrentActivityExecutionSize" toml:"maxConcurrentActivityExecutionSize"`
+	MaxConcurrentLocalActivityExecutionSize int     `yaml:"maxConcurrentLocalActivityExecutionSize" toml:"maxConcurrentLocalActivityExecutionSize"`
+	MaxConcurrentWorkflowTaskExecutionSize  int     `yaml:"maxConcurrentWorkflowTaskExecutionSize" toml:"maxConcurrentWorkflowTaskExecutionSize"`
+	MaxConcurrentActivityTaskPollers        int     `yaml:"maxConcurrentActivityTaskPollers" toml:"maxConcurrentActivityTaskPollers"`
+	MaxConcurrentWorkflowTaskPollers        int     `yaml:"maxConcurrentWorkflowTaskPollers" toml:"maxConcurrentWorkflowTaskPollers"`
+	MaxConcurrentNexusTaskPollers           int     `yaml:"maxConcurrentNexusTaskPollers" toml:"maxConcurrentNexusTaskPollers"`
+	WorkerActivitiesPerSecond               float64 `yaml:"workerActivitiesPerSecond" toml:"workerActivitiesPerSecond"`
+	TaskQueueActivitiesPerSecond            float64 `yaml:"taskQueueActivitiesPerSecond" toml:"taskQueueActivitiesPerSecond"`
+	// WorkerStopTimeout is the time given to in-flight activities when the
+	// worker is drained, e.g. "30s".
+	WorkerStopTimeout string `yaml:"workerStopTimeout" toml:"workerStopTimeout"`
+
+	Workflows     []string `yaml:"workflows" toml:"workflows"`
+	Activities    []string `yaml:"activities" toml:"activities"`
+	NexusServices []string `yaml:"nexusServices" toml:"nexusServices"`
+}
+
+// Dependencies are the services activities are created with.
+type Dependencies struct {
+	Payments   activities.PaymentGateway
+	Inventory  activities.InventoryService
+	Carrier    activities.Carrier
+	Warehouses activities.Warehouses
+}
+
+// workflowRegistrations are the workflows a worker can register, by name.
+// Workflows without a versioning behavior of their own use the worker default.
+var workflowRegistrations = map[string]func(w worker.Worker, defaultBehavior workflow.VersioningBehavior){
+	"OrderWorkflow": func(w worker.Worker, defaultBehavior workflow.VersioningBehavior) {
+		w.RegisterWorkflowWithOptions(workflows.OrderWorkflow, workflow.RegisterOptions{
+			Name: workflows.HAPPY,
+		})
+	},
+	"OrderWorkflowScenarios": func(w worker.Worker, defaultBehavior workflow.VersioningBehavior) {
+		w.RegisterDynamicWorkflow(workflows.OrderWorkflowScenarios, workflow.DynamicRegisterOptions{
+			LoadDynamicRuntimeOptions: func(details workflow.LoadDynamicRuntimeOptionsDetails) (workflow.DynamicRuntimeOptions, error) {
+				return workflow.DynamicRuntimeOptions{
+					VersioningBehavior: workflows.ScenarioVersioningBehavior(details.WorkflowType.Name, defaultBehavior),
+				}, nil
+			},
+		})
+	},
+	"ShippingWorkflow": func(w worker.Worker, defaultBehavior workflow.VersioningBehavior) {
+		w.RegisterWorkflow(workflows.ShippingWorkflow)
+	},
+	"ReturnWorkflow": func(w worker.Worker, defaultBehavior workflow.VersioningBehavior) {
+		w.RegisterWorkflow(workflows.ReturnWorkflow)
+	},
+	"OrderEntityWorkflow": func(w worker.Worker, defaultBehavior workflow.VersioningBehavior) {
+		w.RegisterWorkflow(workflows.OrderEntityWorkflow)
+	},
+}
+
+// activityRegistrations are the activities a worker can register, by name.
+// "Activities" registers the payment, inventory, carrier, return and routing activities.
+var activityRegistrations = map[string]func(w worker.Worker, deps Dependencies){
+	"GetItems":            func(w worker.Worker, deps Dependencies) { w.RegisterActivity(activities.GetItems) },
+	"CheckFraud":          func(w worker.Worker, deps Dependencies) { w.RegisterActivity(activities.CheckFraud) },
+	"PrepareShipment":     func(w worker.Worker, deps Dependencies) { w.RegisterActivity(activities.PrepareShipment) },
+	"UndoPrepareShipment": func(w worker.Worker, deps Dependencies) { w.RegisterActivity(activities.UndoPrepareShipment) },
+	"ShipOrder":           func(w worker.Worker, deps Dependencies) { w.RegisterActivity(activities.ShipOrder) },
+	"InspectReturn":       func(w worker.Worker, deps Dependencies) { w.RegisterActivity(activities.InspectReturn) },
+	"Activities": func(w worker.Worker, deps Dependencies) {
+		w.RegisterActivity(&activities.Activities{
+			Payments:   deps.Payments,
+			Inventory:  deps.Inventory,
+			Carrier:    deps.Carrier,
+			Warehouses: deps.Warehouses,
+		})
+	},
+}
+
+// nexusServiceRegistrations are the Nexus services a worker can register, by name.
+var nexusServiceRegistrations = map[string]func(w worker.Worker) error{
+	app.ShippingServiceName: func(w worker.Worker) error {
+		service := nexus.NewService(app.ShippingServiceName)
+		err := service.Register(handler.ShippingOperation)
+		if err != nil {
+			return err
+		}
+		w.RegisterNexusService(service)
+		return nil
+	},
+}
+
+// DefaultConfig is used without a config file: a single worker on
+// TEMPORAL_TASK_QUEUE running the order workflows and all of their activities.
+func DefaultConfig() *Config {
+	return &Config{
+		Workers: []WorkerConfig{{
+			TaskQueue:         app.GetEnv("TEMPORAL_TASK_QUEUE", "orders"),
+			WorkerStopTimeout: "30s",
+			Workflows:         []string{"OrderWorkflow", "OrderWorkflowScenarios", "ShippingWorkflow", "ReturnWorkflow", "OrderEntityWorkflow"},
+			Activities:        []string{"GetItems", "CheckFraud", "PrepareShipment", "UndoPrepareShipment", "Activities", "ShipOrder", "InspectReturn"},
+		}},
+	}
+}
+
+// ApplyEnv overrides the config with TEMPORAL_DEPLOYMENT_NAME,
+// TEMPORAL_WORKER_BUILD_ID, ORDER_WORKFLOW_VERSION and
+// PATCH_RECOVERABLE_FAILURE, which are usually set per build at deploy time.
+func (c *Config) ApplyEnv() error {
+	c.Deployment.Name = app.GetEnv("TEMPORAL_DEPLOYMENT_NAME", c.Deployment.Name)
+	c.Deployment.BuildId = app.GetEnv("TEMPORAL_WORKER_BUILD_ID", c.Deployment.BuildId)
+	version := app.GetEnv("ORDER_WORKFLOW_VERSION", "")
+	if version != "" {
+		v, err := strconv.Atoi(version)
+		if err != nil {
+			return fmt.Errorf("invalid ORDER_WORKFLOW_VERSION: %w", err)
+		}
+		c.OrderWorkflowVersion = v
+	}
+	patch := app.GetEnv("PATCH_RECOVERABLE_FAILURE", "")
+	if patch != "" {
+		p, err := strconv.ParseBool(patch)
+		if err != nil {
+			return fmt.Errorf("invalid PATCH_RECOVERABLE_FAILURE: %w", err)
+		}
+		c.PatchRecoverableFailure = p
+	}
+	return c.Validate()
+}
+
+// LoadConfigFile reads a YAML, or TOML if path ends in .toml, config file.
+func LoadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(data, &config)
+	} else {
+		err = yaml.Unmarshal(data, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid worker config %v: %w", path, err)
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid worker config %v: %w", path, err)
+	}
+	return &config, nil
+}
+
+// Validate checks every worker has a unique task queue and only registers
+// known workflows, activities and Nexus services.
+func (c *Config) Validate() error {
+	if len(c.Workers) == 0 {
+		return fmt.Errorf("no workers configured")
+	}
+	if c.OrderWorkflowVersion < 0 || c.OrderWorkflowVersion > 2 {
+		return fmt.Errorf("unknown orderWorkflowVersion %v, expected 1 or 2", c.OrderWorkflowVersion)
+	}
+	if c.Deployment.Name != "" && c.Deployment.BuildId == "" {
+		return fmt.Errorf("deployment %q requires a buildId", c.Deployment.Name)
+	}
+	if _, err := c.Deployment.versioningBehavior(); err != nil {
+		return err
+	}
+	if err := c.Faults.Validate(); err != nil {
+		return err
+	}
+	var taskQueues []string
+	for _, wc := range c.Workers {
+		if wc.TaskQueue == "" {
+			return fmt.Errorf("worker without a taskQueue")
+		}
+		if slices.Contains(taskQueues, wc.TaskQueue) {
+			return fmt.Errorf("task queue %q configured more than once", wc.TaskQueue)
+		}
+		taskQueues = append(taskQueues, wc.TaskQueue)
+
+		if len(wc.Workflows)+len(wc.Activities)+len(wc.NexusServices) == 0 {
+			return fmt.Errorf("worker for %q registers nothing", wc.TaskQueue)
+		}
+		for _, name := range wc.Workflows {
+			if _, ok := workflowRegistrations[name]; !ok {
+				return fmt.Errorf("unknown workflow %q for %q", name, wc.TaskQueue)
+			}
+		}
+		for _, name := range wc.Activities {
+			if _, ok := activityRegistrations[name]; !ok {
+				return fmt.Errorf("unknown activity %q for %q", name, wc.TaskQueue)
+			}
+		}
+		for _, name := range wc.NexusServices {
+			if _, ok := nexusServiceRegistrations[name]; !ok {
+				return fmt.Errorf("unknown Nexus service %q for %q", name, wc.TaskQueue)
+			}
+		}
+		if wc.WorkerStopTimeout != "" {
+			if _, err := time.ParseDuration(wc.WorkerStopTimeout); err != nil {
+				return fmt.Errorf("invalid workerStopTimeout for %q: %w", wc.TaskQueue, err)
+			}
+		}
+	}
+	return nil
+}
+
+// versioningBehavior returns the default versioning behavior of the
+// deployment, which is unspecified without versioning.
+func (d DeploymentConfig) versioningBehavior() (workflow.VersioningBehavior, error) {
+	if d.Name == "" {
+		return workflow.VersioningBehaviorUnspecified, nil
+	}
+	switch d.DefaultVersioningBehavior {
+	case "", "pinned":
+		return workflow.VersioningBehaviorPinned, nil
+	case "auto-upgrade":
+		return workflow.VersioningBehaviorAutoUpgrade, nil
+	default:
+		return workflow.VersioningBehaviorUnspecified, fmt.Errorf("unknown defaultVersioningBehavior %q, expected pinned or auto-upgrade", d.DefaultVersioningBehavior)
+	}
+}
+
+// Options returns the SDK worker options for the worker, in deployment d.
+func (wc WorkerConfig) Options(d DeploymentConfig) worker.Options {
+	// Validate has already checked the timeout and versioning behavior
+	stopTimeout, _ := time.ParseDuration(wc.WorkerStopTimeout)
+	behavior, _ := d.versioningBehavior()
+	return worker.Options{
+		DeploymentOptions: worker.DeploymentOptions{
+			UseVersioning: d.Name != "",
+			Version: worker.WorkerDeploymentVersion{
+				DeploymentName: d.Name,
+				BuildID:        d.BuildId,
+			},
+			DefaultVersioningBehavior: behavior,
+		},
+		MaxConcurrentActivityExecutionSize:      wc.MaxConcurrentActivityExecutionSize,
+		MaxConcurrentLocalActivityExecutionSize: wc.MaxConcurrentLocalActivityExecutionSize,
+		MaxConcurrentWorkflowTaskExecutionSize:  wc.MaxConcurrentWorkflowTaskExecutionSize,
+		MaxConcurrentActivityTaskPollers:        wc.MaxConcurrentActivityTaskPollers,
+		MaxConcurrentWorkflowTaskPollers:        wc.MaxConcurrentWorkflowTaskPollers,
+		MaxConcurrentNexusTaskPollers:           wc.MaxConcurrentNexusTaskPollers,
+		WorkerActivitiesPerSecond:               wc.WorkerActivitiesPerSecond,
+		TaskQueueActivitiesPerSecond:            wc.TaskQueueActivitiesPerSecond,
+		WorkerStopTimeout:                       stopTimeout,
+	}
+}
+
+// Register registers the configured workflows, activities and Nexus services
+// with w, a worker in deployment d.
+func (wc WorkerConfig) Register(w worker.Worker, d DeploymentConfig, deps Dependencies) error {
+	behavior, _ := d.versioningBehavior()
+	for _, name := range wc.Workflows {
+		workflowRegistrations[name](w, behavior)
+	}
+	for _, name := range wc.Activities {
+		activityRegistrations[name](w, deps)
+	}
+	for _, name := range wc.NexusServices {
+		err := nexusServiceRegistrations[name](w)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usesActivities reports whether any worker registers one of names.
+func (c *Config) usesActivities(names ...string) bool {
+	for _, wc := range c.Workers {
+		for _, name := range names {
+			if slices.Contains(wc.Activities, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"temporal-order-management/app"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestLoadExampleConfigs(t *testing.T) {
+	files, err := filepath.Glob("config/*")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			config, err := LoadConfigFile(file)
+			require.NoError(t, err)
+			require.NotEmpty(t, config.Workers)
+		})
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	config, err := LoadConfigFile("config/orders.yaml")
+	require.NoError(t, err)
+	require.Equal(t, 2000, config.StickyCacheSize)
+	require.Len(t, config.Workers, 1)
+
+	options := config.Workers[0].Options(config.Deployment)
+	require.Equal(t, 200, options.MaxConcurrentActivityExecutionSize)
+	require.Equal(t, 4, options.MaxConcurrentWorkflowTaskPollers)
+	require.Equal(t, 50.0, options.TaskQueueActivitiesPerSecond)
+	require.Equal(t, 30*time.Second, options.WorkerStopTimeout)
+
+	config, err = LoadConfigFile("config/dev.toml")
+	require.NoError(t, err)
+	require.Equal(t, 100, config.StickyCacheSize)
+	require.Equal(t, "orders", config.Workers[0].TaskQueue)
+	require.Equal(t, []string{"shipping-service"}, config.Workers[0].NexusServices)
+	require.Equal(t, 5*time.Second, config.Workers[0].Options(config.Deployment).WorkerStopTimeout)
+}
+
+func TestLoadConfigFileRejectsInvalidConfig(t *testing.T) {
+	tests := map[string]string{
+		"no workers":        "stickyCacheSize: 10\n",
+		"no task queue":     "workers:\n  - workflows: [OrderWorkflow]\n",
+		"unknown workflow":  "workers:\n  - taskQueue: orders\n    workflows: [RefundWorkflow]\n",
+		"unknown activity":  "workers:\n  - taskQueue: orders\n    activities: [RefundCustomer]\n",
+		"registers nothing": "workers:\n  - taskQueue: orders\n",
+		"duplicate task queue": "workers:\n  - taskQueue: orders\n    workflows: [OrderWorkflow]\n" +
+			"  - taskQueue: orders\n    activities: [ShipOrder]\n",
+		"bad stop timeout": "workers:\n  - taskQueue: orders\n    workerStopTimeout: soon\n    workflows: [OrderWorkflow]\n",
+		"unknown fault": "faults:\n  - activity: ShipOrder\n    fault: meteorStrike\n" +
+			"workers:\n  - taskQueue: orders\n    workflows: [OrderWorkflow]\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "worker.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+			_, err := LoadConfigFile(path)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestLoadConfigFileFaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "worker.toml")
+	data := `
+[[faults]]
+activity = "PrepareShipment"
+fault = "latency"
+delay = "3s"
+
+[[workers]]
+taskQueue = "orders"
+activities = ["PrepareShipment"]
+`
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+	config, err := LoadConfigFile(path)
+	require.NoError(t, err)
+	require.Equal(t, app.FaultRules{{Activity: "PrepareShipment", Fault: app.FaultLatency, Delay: app.Duration(3 * time.Second)}}, config.Faults)
+}
+
+func TestDefaultConfigRegistersEverything(t *testing.T) {
+	t.Setenv("TEMPORAL_TASK_QUEUE", "orders-test")
+	config := DefaultConfig()
+	require.NoError(t, config.Validate())
+	require.Equal(t, "orders-test", config.Workers[0].TaskQueue)
+	require.True(t, config.usesActivities("Activities"))
+}
+
+func TestDeploymentOptions(t *testing.T) {
+	t.Setenv("TEMPORAL_DEPLOYMENT_NAME", "orders")
+	t.Setenv("TEMPORAL_WORKER_BUILD_ID", "v2")
+	t.Setenv("ORDER_WORKFLOW_VERSION", "2")
+	config := DefaultConfig()
+	require.NoError(t, config.ApplyEnv())
+	require.Equal(t, 2, config.OrderWorkflowVersion)
+
+	options := config.Workers[0].Options(config.Deployment)
+	require.True(t, options.DeploymentOptions.UseVersioning)
+	require.Equal(t, "orders", options.DeploymentOptions.Version.DeploymentName)
+	require.Equal(t, "v2", options.DeploymentOptions.Version.BuildID)
+	require.Equal(t, workflow.VersioningBehaviorPinned, options.DeploymentOptions.DefaultVersioningBehavior)
+
+	config.Deployment.DefaultVersioningBehavior = "sometimes"
+	require.Error(t, config.Validate())
+
+	t.Setenv("TEMPORAL_WORKER_BUILD_ID", "")
+	require.Error(t, DefaultConfig().ApplyEnv())
+}
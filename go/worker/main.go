@@ -2,17 +2,25 @@ package main
 
 import (
 	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
 	"temporal-order-management/activities"
 	"temporal-order-management/app"
 	"temporal-order-management/workflows"
+	"time"
 
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
-	"go.temporal.io/sdk/workflow"
 )
 
 func main() {
-	co := app.GetClientOptions()
+	co, rotator := app.GetClientOptions(app.NewMetricsHandler(app.GetEnv("METRICS_ADDRESS", "0.0.0.0:9090")))
+	// API keys can be rotated without restarting through the "kms" endpoint
+	app.StartAPIKeyRotationServer(rotator, slog.Default())
+	shutdownTracing := app.ConfigureTracing(&co, "orders-worker")
+	defer shutdownTracing()
 	c, err := client.Dial(co)
 	if err != nil {
 		log.Fatalln("Unable to create client", err)
@@ -20,26 +28,142 @@ func main() {
 	log.Printf("✅ Client connected to %v in namespace '%v'", co.HostPort, co.Namespace)
 	defer c.Close()
 
-	w := worker.New(c, app.GetEnv("TEMPORAL_TASK_QUEUE", "orders"), worker.Options{})
-
-	// workflows
-	w.RegisterWorkflowWithOptions(workflows.OrderWorkflow, workflow.RegisterOptions{
-		Name: "OrderWorkflowHappyPath",
-	})
-	w.RegisterDynamicWorkflow(workflows.OrderWorkflowScenarios, workflow.DynamicRegisterOptions{})
-	w.RegisterWorkflow(workflows.ShippingWorkflow)
-
-	// activities
-	w.RegisterActivity(activities.GetItems)
-	w.RegisterActivity(activities.CheckFraud)
-	w.RegisterActivity(activities.PrepareShipment)
-	w.RegisterActivity(activities.UndoPrepareShipment)
-	w.RegisterActivity(activities.ChargeCustomer)
-	w.RegisterActivity(activities.UndoChargeCustomer)
-	w.RegisterActivity(activities.ShipOrder)
-
-	err = w.Run(worker.InterruptCh())
+	err = workflows.LoadScenarios()
 	if err != nil {
-		log.Fatalln("Unable to start worker", err)
+		log.Fatalln("Unable to load scenarios", err)
 	}
+
+	config := DefaultConfig()
+	configFile := app.GetEnv("WORKER_CONFIG", "")
+	if configFile != "" {
+		config, err = LoadConfigFile(configFile)
+		if err != nil {
+			log.Fatalln("Unable to load worker config", err)
+		}
+	}
+	err = config.ApplyEnv()
+	if err != nil {
+		log.Fatalln("Invalid worker config", err)
+	}
+	if config.OrderWorkflowVersion > 0 {
+		workflows.OrderWorkflowVersion = config.OrderWorkflowVersion
+	}
+	workflows.PatchRecoverableFailure = config.PatchRecoverableFailure
+	if config.StickyCacheSize > 0 {
+		worker.SetStickyWorkflowCacheSize(config.StickyCacheSize)
+	}
+
+	var deps Dependencies
+	if config.usesActivities("GetItems", "Activities") {
+		catalogFile := app.GetEnv("CATALOG_FILE", "")
+		if catalogFile != "" {
+			catalog, err := activities.LoadCatalogFile(catalogFile)
+			if err != nil {
+				log.Fatalln("Unable to load catalog", err)
+			}
+			activities.SetCatalog(catalog)
+		}
+	}
+	if config.usesActivities("Activities") {
+		// payments must outlive the worker, and be shared with the other
+		// workers, or orders can't capture or undo their authorization
+		paymentGatewayURL := app.GetEnv("PAYMENT_GATEWAY_URL", "")
+		if paymentGatewayURL != "" {
+			deps.Payments = activities.NewHTTPPaymentGateway(paymentGatewayURL)
+		} else {
+			paymentsFile := app.GetEnv("PAYMENTS_FILE", filepath.Join(os.TempDir(), "temporal-order-management-payments.json"))
+			deps.Payments, err = activities.NewFilePaymentGateway(paymentsFile)
+			if err != nil {
+				log.Fatalln("Unable to load payments", err)
+			}
+		}
+
+		deps.Inventory = activities.NewMemoryInventory(activities.DefaultStock)
+		inventoryFile := app.GetEnv("INVENTORY_FILE", "")
+		if inventoryFile != "" {
+			deps.Inventory, err = activities.NewFileInventory(inventoryFile, activities.DefaultStock)
+			if err != nil {
+				log.Fatalln("Unable to load inventory", err)
+			}
+		}
+
+		warehousesFile := app.GetEnv("WAREHOUSES_FILE", "")
+		if warehousesFile != "" {
+			deps.Warehouses, err = activities.LoadWarehousesFile(warehousesFile)
+			if err != nil {
+				log.Fatalln("Unable to load warehouses", err)
+			}
+		}
+
+		// without a carrier simulator, deliveries are confirmed in process
+		deliveryDelay, err := time.ParseDuration(app.GetEnv("CARRIER_DELIVERY_DELAY", "10s"))
+		if err != nil {
+			log.Fatalln("Invalid CARRIER_DELIVERY_DELAY", err)
+		}
+		deps.Carrier = activities.NewCarrierSimulator(c, deliveryDelay)
+		carrierURL := app.GetEnv("CARRIER_URL", "")
+		if carrierURL != "" {
+			deps.Carrier = activities.NewHTTPCarrier(carrierURL)
+		}
+	}
+
+	if config.Deployment.Name != "" {
+		log.Printf("Worker Deployment '%v' build '%v', order workflow v%v",
+			config.Deployment.Name, config.Deployment.BuildId, workflows.OrderWorkflowVersion)
+	}
+
+	// fault rules can also come from the memo of an order, so the injector is
+	// always installed
+	faults := app.NewFaultInjector(config.Faults, co.DataConverter)
+	if len(config.Faults) > 0 {
+		log.Printf("Injecting faults with %v rules", len(config.Faults))
+	}
+
+	var workers workerGroup
+	for _, wc := range config.Workers {
+		options := wc.Options(config.Deployment)
+		options.Interceptors = append(options.Interceptors, faults)
+		w := worker.New(c, wc.TaskQueue, options)
+		err = wc.Register(w, config.Deployment, deps)
+		if err != nil {
+			log.Fatalln("Unable to register worker", err)
+		}
+		workers = append(workers, w)
+		log.Printf("Worker for task queue '%v': workflows %v, activities %v, nexus services %v",
+			wc.TaskQueue, wc.Workflows, wc.Activities, wc.NexusServices)
+	}
+
+	admin := app.NewAdminServer(c, workers, app.GetEnv("ADMIN_DRAIN_SECRET", ""))
+	admin.Start(app.GetEnv("ADMIN_ADDRESS", "0.0.0.0:8090"))
+
+	for _, w := range workers {
+		err = w.Start()
+		if err != nil {
+			log.Fatalln("Unable to start worker", err)
+		}
+	}
+	admin.SetStarted()
+
+	// Stop on interrupt, or once drained through the admin server
+	select {
+	case <-worker.InterruptCh():
+	case <-admin.Drained():
+	}
+	admin.Drain()
+}
+
+// workerGroup stops all of the workers in the process together.
+type workerGroup []worker.Worker
+
+// Stop stops the workers concurrently, so each gets its full WorkerStopTimeout.
+func (g workerGroup) Stop() {
+	var wg sync.WaitGroup
+	for _, w := range g {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Stop()
+		}()
+	}
+	wg.Wait()
 }
@@ -0,0 +1,36 @@
+package workflows
+
+import (
+	"strings"
+	"temporal-order-management/app"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/workflow"
+)
+
+// orderMetrics returns the workflow metrics handler tagged with the order
+// scenario, e.g. "HappyPath". Metrics are not emitted during replay.
+func orderMetrics(ctx workflow.Context) client.MetricsHandler {
+	scenario := strings.TrimPrefix(workflow.GetInfo(ctx).WorkflowType.Name, "OrderWorkflow")
+	return workflow.GetMetricsHandler(ctx).WithTags(map[string]string{"scenario": scenario})
+}
+
+func recordOrderStarted(ctx workflow.Context) {
+	orderMetrics(ctx).Counter(app.MetricOrdersStarted).Inc(1)
+}
+
+// recordOrderResult counts how the order ended and, for completed orders, the
+// time from the order being placed to it completing. An order handed over to
+// its entity is completed.
+func recordOrderResult(ctx workflow.Context, output *app.OrderOutput, err error) {
+	metrics := orderMetrics(ctx)
+	switch {
+	case err != nil && !workflow.IsContinueAsNewError(err):
+		metrics.Counter(app.MetricOrdersFailed).Inc(1)
+	case output != nil && output.Status == app.OrderStatusCancelled:
+		metrics.Counter(app.MetricOrdersCancelled).Inc(1)
+	default:
+		metrics.Counter(app.MetricOrdersCompleted).Inc(1)
+		metrics.Timer(app.MetricOrderLatency).Record(workflow.Now(ctx).Sub(workflow.GetInfo(ctx).WorkflowStartTime))
+	}
+}
@@ -0,0 +1,84 @@
+package workflows
+
+import (
+	"errors"
+	"temporal-order-management/activities"
+	"temporal-order-management/app"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally/v4"
+	sdktally "go.temporal.io/sdk/contrib/tally"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func newMetricsTestEnvironment(scope tally.TestScope) *testsuite.TestWorkflowEnvironment {
+	var ts testsuite.WorkflowTestSuite
+	ts.SetMetricsHandler(sdktally.NewMetricsHandler(scope))
+	return newTestEnvironment(&ts, nil)
+}
+
+// counterValue sums the counters with name that have all of tags.
+func counterValue(scope tally.TestScope, name string, tags map[string]string) int64 {
+	var total int64
+	for _, counter := range scope.Snapshot().Counters() {
+		if counter.Name() != name || !hasTags(counter.Tags(), tags) {
+			continue
+		}
+		total += counter.Value()
+	}
+	return total
+}
+
+func hasTags(actual, expected map[string]string) bool {
+	for k, v := range expected {
+		if actual[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOrderMetrics_Completed(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	env := newMetricsTestEnvironment(scope)
+	mockActivities(env)
+
+	env.ExecuteWorkflow(HAPPY, testOrder())
+	require.NoError(t, env.GetWorkflowError())
+
+	scenario := map[string]string{"scenario": "HappyPath"}
+	require.Equal(t, int64(1), counterValue(scope, app.MetricOrdersStarted, scenario))
+	require.Equal(t, int64(1), counterValue(scope, app.MetricOrdersCompleted, scenario))
+	require.Equal(t, int64(0), counterValue(scope, app.MetricOrdersFailed, scenario))
+
+	var latency bool
+	for _, histogram := range scope.Snapshot().Histograms() {
+		latency = latency || histogram.Name() == app.MetricOrderLatency
+	}
+	for _, timer := range scope.Snapshot().Timers() {
+		latency = latency || timer.Name() == app.MetricOrderLatency
+	}
+	require.True(t, latency, "order latency not recorded")
+}
+
+func TestOrderMetrics_FailedWithCompensations(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	env := newMetricsTestEnvironment(scope)
+	var a *activities.Activities
+	env.OnActivity(a.ChargeCustomer, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("", temporal.NewNonRetryableApplicationError("charge customer activity failed", "activityFailure", errors.New("credit card invalid")))
+	mockActivities(env)
+
+	env.ExecuteWorkflow(NONRECOVERABLE, testOrder())
+	require.Error(t, env.GetWorkflowError())
+
+	scenario := map[string]string{"scenario": "NonRecoverableFailure"}
+	require.Equal(t, int64(1), counterValue(scope, app.MetricOrdersStarted, scenario))
+	require.Equal(t, int64(1), counterValue(scope, app.MetricOrdersFailed, scenario))
+	require.Equal(t, int64(0), counterValue(scope, app.MetricOrdersCompleted, scenario))
+	require.Equal(t, int64(1), counterValue(scope, app.MetricCompensations, map[string]string{"step": "UndoChargeCustomer", "outcome": "completed"}))
+	require.Equal(t, int64(1), counterValue(scope, app.MetricCompensations, map[string]string{"step": "ReleaseInventory", "outcome": "completed"}))
+}
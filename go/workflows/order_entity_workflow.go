@@ -0,0 +1,172 @@
+package workflows
+
+import (
+	"fmt"
+	"slices"
+	"temporal-order-management/app"
+	"temporal-order-management/messages"
+	"time"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/workflow"
+)
+
+// OrderEntityHistoryThreshold is the history length, in events, at which an
+// order entity continues as new, unless the server suggests it sooner.
+var OrderEntityHistoryThreshold = 2000
+
+// OrderEntityState is the input of OrderEntityWorkflow, carried over each time
+// the entity continues as new.
+type OrderEntityState struct {
+	Order  app.OrderInput       `json:"order"`
+	Output app.OrderOutput      `json:"output"`
+	Status messages.OrderStatus `json:"status"`
+	// CloseAt is when the return window closes and the entity completes
+	CloseAt time.Time `json:"closeAt"`
+}
+
+// NewOrderEntityState returns the state of the entity of a completed order.
+func NewOrderEntityState(ctx workflow.Context, input app.OrderInput, output app.OrderOutput, status messages.OrderStatus) OrderEntityState {
+	status.Address = output.Address
+	return OrderEntityState{
+		Order:   input,
+		Output:  output,
+		Status:  status,
+		CloseAt: workflow.Now(ctx).Add(ReturnWindow),
+	}
+}
+
+// OrderEntityWorkflow keeps a completed order running until its return window
+// closes, handling delivery events, address corrections, notes and returns.
+// The order status is carried over when the entity continues as new, so the
+// getOrderStatus query answers the same across runs.
+func OrderEntityWorkflow(ctx workflow.Context, state OrderEntityState) (*app.OrderOutput, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Order entity started", "orderId", state.Order.OrderId, "continuedAsNew", state.Status.ContinuedAsNew)
+
+	// Expose progress and order status as queries, restored from the last run
+	progress, err := messages.SetQueryHandlerForProgress(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status, err := messages.SetQueryHandlerForOrderStatus(ctx, state.Order.OrderId, progress)
+	if err != nil {
+		return nil, err
+	}
+	*status = state.Status
+	*progress = state.Status.Progress
+
+	// Correct the address until every item is delivered
+	canCorrect := func() bool {
+		return !status.Delivered()
+	}
+	correctAddress := func(updateInput messages.UpdateOrderInput) {
+		logger.Info("Correcting address", "address", updateInput.Address)
+		state.Output.Address = updateInput.Address
+		status.Address = updateInput.Address
+	}
+	err = messages.SetUpdateHandlerForAddressCorrection(ctx, canCorrect, correctAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	err = messages.SetUpdateHandlerForAddNote(ctx, func(ctx workflow.Context, noteInput messages.AddNoteInput) {
+		status.Notes = append(status.Notes, messages.Note{
+			Author:  noteInput.Author,
+			Text:    noteInput.Text,
+			AddedAt: workflow.Now(ctx),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Start a ReturnWorkflow per returned item. Returns are abandoned by the
+	// entity, so they outlive its runs.
+	canReturn := func(returnInput messages.RequestReturnInput) error {
+		returnId := app.ReturnWorkflowId(state.Order.OrderId, returnInput.ItemId)
+		if slices.Contains(status.Returns, returnId) {
+			return fmt.Errorf("item %v is already being returned", returnInput.ItemId)
+		}
+		_, err := app.NewReturnInput(state.Order.OrderId, state.Output, returnInput.ItemId, returnInput.Reason)
+		return err
+	}
+	requestReturn := func(ctx workflow.Context, returnInput messages.RequestReturnInput) (string, error) {
+		input, err := app.NewReturnInput(state.Order.OrderId, state.Output, returnInput.ItemId, returnInput.Reason)
+		if err != nil {
+			return "", err
+		}
+		cwo := workflow.ChildWorkflowOptions{
+			WorkflowID:        app.ReturnWorkflowId(state.Order.OrderId, returnInput.ItemId),
+			ParentClosePolicy: enums.PARENT_CLOSE_POLICY_ABANDON,
+		}
+		var execution workflow.Execution
+		err = workflow.ExecuteChildWorkflow(workflow.WithChildOptions(ctx, cwo), ReturnWorkflow, input).
+			GetChildWorkflowExecution().Get(ctx, &execution)
+		if err != nil {
+			return "", err
+		}
+		status.Returns = append(status.Returns, execution.ID)
+		return execution.ID, nil
+	}
+	err = messages.SetUpdateHandlerForRequestReturn(ctx, canReturn, requestReturn)
+	if err != nil {
+		return nil, err
+	}
+
+	// Handle signals on the main coroutine, so none are left unhandled when the
+	// run ends
+	deliveryEvents := messages.GetSignalChannelForDeliveryEvent(ctx)
+	addressSignals := messages.GetSignalChannelForUpdateOrder(ctx)
+	handleSignals := func() {
+		var event messages.DeliveryEventInput
+		for deliveryEvents.ReceiveAsync(&event) {
+			status.AddDeliveryEvent(ctx, event)
+		}
+		var updateInput messages.UpdateOrderInput
+		for addressSignals.ReceiveAsync(&updateInput) {
+			if !canCorrect() {
+				logger.Info("Ignoring address signal, order is already delivered")
+				continue
+			}
+			correctAddress(updateInput)
+		}
+	}
+
+	continueAsNew := func() bool {
+		info := workflow.GetInfo(ctx)
+		return info.GetContinueAsNewSuggested() || info.GetCurrentHistoryLength() >= OrderEntityHistoryThreshold
+	}
+	closed := workflow.NewTimer(ctx, state.CloseAt.Sub(workflow.Now(ctx)))
+	for !closed.IsReady() && !continueAsNew() {
+		err = workflow.Await(ctx, func() bool {
+			return deliveryEvents.Len() > 0 || addressSignals.Len() > 0 || closed.IsReady() || continueAsNew()
+		})
+		if err != nil {
+			return nil, err
+		}
+		handleSignals()
+	}
+
+	// Finish updates in flight, e.g. a return being started
+	err = workflow.Await(ctx, func() bool {
+		return workflow.AllHandlersFinished(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	handleSignals()
+
+	if closed.IsReady() {
+		logger.Info("Return window closed, order entity completed", "orderId", state.Order.OrderId)
+		status.StartStep(ctx, "Order Closed")
+		output := state.Output
+		return &output, nil
+	}
+
+	logger.Info("Order entity continuing as new", "historyLength", workflow.GetInfo(ctx).GetCurrentHistoryLength())
+	status.ContinuedAsNew++
+	state.Status = *status
+	state.Status.Progress = *progress
+	return nil, workflow.NewContinueAsNewError(ctx, OrderEntityWorkflow, state)
+}
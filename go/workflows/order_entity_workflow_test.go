@@ -0,0 +1,161 @@
+package workflows
+
+import (
+	"errors"
+	"temporal-order-management/app"
+	"temporal-order-management/messages"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func newEntityTestEnvironment() (*testsuite.TestWorkflowEnvironment, OrderEntityState) {
+	var s testsuite.WorkflowTestSuite
+	env := newTestEnvironment(&s, nil)
+
+	items := testItems()
+	output := app.OrderOutput{
+		TrackingId: "tracking-1",
+		Address:    testOrder().Address,
+		Status:     app.OrderStatusCompleted,
+		Items:      items,
+		Payment:    &app.Payment{AuthorizationId: "auth-1", Amount: items.Total(), Captured: true},
+	}
+	status := messages.OrderStatus{OrderId: testOrder().OrderId, Progress: 100, CurrentStep: "Order Completed"}
+	status.SetItems(items)
+	for i := range items {
+		status.SetItemStatus(i, messages.ItemStatusShipped)
+	}
+	state := OrderEntityState{
+		Order:   testOrder(),
+		Output:  output,
+		Status:  status,
+		CloseAt: env.Now().Add(ReturnWindow),
+	}
+	state.Status.Address = output.Address
+	return env, state
+}
+
+type updateResult struct {
+	rejected error
+	value    interface{}
+	err      error
+}
+
+// scheduleUpdate sends an update to the entity after delay.
+func scheduleUpdate(env *testsuite.TestWorkflowEnvironment, delay time.Duration, name string, arg interface{}) *updateResult {
+	result := &updateResult{}
+	env.RegisterDelayedCallback(func() {
+		env.UpdateWorkflow(name, "", &testsuite.TestUpdateCallback{
+			OnAccept: func() {},
+			OnReject: func(err error) { result.rejected = err },
+			OnComplete: func(value interface{}, err error) {
+				result.value = value
+				result.err = err
+			},
+		}, arg)
+	}, delay)
+	return result
+}
+
+func entityStatus(t *testing.T, env *testsuite.TestWorkflowEnvironment) messages.OrderStatus {
+	value, err := env.QueryWorkflow("getOrderStatus")
+	require.NoError(t, err)
+	var status messages.OrderStatus
+	require.NoError(t, value.Get(&status))
+	return status
+}
+
+func TestOrderEntityWorkflow_HandlesMessagesUntilReturnWindowCloses(t *testing.T) {
+	env, state := newEntityTestEnvironment()
+	env.OnWorkflow(ReturnWorkflow, mock.Anything, mock.Anything).
+		Return(&app.ReturnOutput{Status: messages.ReturnStatusRefunded}, nil).Once()
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("DeliveryEvent", messages.DeliveryEventInput{Event: messages.DeliveryEventOutForDelivery})
+	}, time.Hour)
+	corrected := scheduleUpdate(env, 2*time.Hour, "UpdateOrder", messages.UpdateOrderInput{Address: "456 Oak Ave"})
+	note := scheduleUpdate(env, 3*time.Hour, "AddNote", messages.AddNoteInput{Author: "Alice Jones", Text: "leave at the door"})
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("DeliveryEvent", messages.DeliveryEventInput{Event: messages.DeliveryEventDelivered})
+	}, 4*time.Hour)
+	tooLate := scheduleUpdate(env, 5*time.Hour, "UpdateOrder", messages.UpdateOrderInput{Address: "789 Pine Rd"})
+	returned := scheduleUpdate(env, 6*time.Hour, "RequestReturn", messages.RequestReturnInput{ItemId: 654321, Reason: "wrong size"})
+	returnedAgain := scheduleUpdate(env, 7*time.Hour, "RequestReturn", messages.RequestReturnInput{ItemId: 654321, Reason: "wrong size"})
+
+	env.ExecuteWorkflow(OrderEntityWorkflow, state)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var output app.OrderOutput
+	require.NoError(t, env.GetWorkflowResult(&output))
+	require.Equal(t, "456 Oak Ave", output.Address)
+
+	require.NoError(t, corrected.rejected)
+	require.NoError(t, note.rejected)
+	require.Error(t, tooLate.rejected)
+	require.NoError(t, returned.rejected)
+	require.NoError(t, returned.err)
+	require.Equal(t, "return-123456-654321", returned.value)
+	require.Error(t, returnedAgain.rejected)
+
+	status := entityStatus(t, env)
+	require.Equal(t, "Order Closed", status.CurrentStep)
+	require.Equal(t, "456 Oak Ave", status.Address)
+	require.Len(t, status.DeliveryEvents, 2)
+	require.True(t, status.Delivered())
+	require.Equal(t, []string{"return-123456-654321"}, status.Returns)
+	require.Len(t, status.Notes, 1)
+	require.Equal(t, "leave at the door", status.Notes[0].Text)
+}
+
+func TestOrderEntityWorkflow_ContinuesAsNewWithStatus(t *testing.T) {
+	env, state := newEntityTestEnvironment()
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("DeliveryEvent", messages.DeliveryEventInput{ItemId: 654300, Event: messages.DeliveryEventDelivered})
+	}, time.Hour)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("DeliveryEvent", messages.DeliveryEventInput{ItemId: 654321, Event: messages.DeliveryEventOutForDelivery})
+	}, 2*time.Hour)
+	env.RegisterDelayedCallback(func() {
+		env.SetCurrentHistoryLength(OrderEntityHistoryThreshold)
+	}, 3*time.Hour)
+
+	env.ExecuteWorkflow(OrderEntityWorkflow, state)
+
+	require.True(t, env.IsWorkflowCompleted())
+	var canErr *workflow.ContinueAsNewError
+	require.True(t, errors.As(env.GetWorkflowError(), &canErr))
+	require.Equal(t, "OrderEntityWorkflow", canErr.WorkflowType.Name)
+	var next OrderEntityState
+	require.NoError(t, converter.GetDefaultDataConverter().FromPayloads(canErr.Input, &next))
+	require.Equal(t, 1, next.Status.ContinuedAsNew)
+	require.Equal(t, 100, next.Status.Progress)
+	require.Len(t, next.Status.DeliveryEvents, 2)
+	require.Equal(t, messages.ItemStatusDelivered, next.Status.Items[0].Status)
+	require.Equal(t, messages.ItemStatusShipped, next.Status.Items[1].Status)
+	require.True(t, state.CloseAt.Equal(next.CloseAt))
+}
+
+func TestOrderEntityWorkflow_RestoresStatus(t *testing.T) {
+	env, state := newEntityTestEnvironment()
+	state.Status.ContinuedAsNew = 2
+	state.Status.Notes = []messages.Note{{Text: "gift wrap please"}}
+	state.CloseAt = env.Now().Add(time.Hour)
+
+	env.ExecuteWorkflow(OrderEntityWorkflow, state)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	status := entityStatus(t, env)
+	require.Equal(t, 2, status.ContinuedAsNew)
+	require.Equal(t, 100, status.Progress)
+	require.Equal(t, state.Status.Notes, status.Notes)
+	require.Len(t, status.CompletedSteps, 1)
+	require.Equal(t, "Order Completed", status.CompletedSteps[0].Name)
+}
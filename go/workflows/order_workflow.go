@@ -15,6 +15,10 @@ func OrderWorkflow(ctx workflow.Context, input app.OrderInput) (output *app.Orde
 	name := workflow.GetInfo(ctx).WorkflowType.Name
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Processing order started", "orderId", input.OrderId)
+	recordOrderStarted(ctx)
+	defer func() {
+		recordOrderResult(ctx, output, err)
+	}()
 
 	activityOptions := workflow.ActivityOptions{
 		StartToCloseTimeout: 5 * time.Second,
@@ -31,66 +35,144 @@ func OrderWorkflow(ctx workflow.Context, input app.OrderInput) (output *app.Orde
 	}
 	laCtx := workflow.WithLocalActivityOptions(ctx, localActivityOptions)
 
-	// Expose progress as query
+	var a *activities.Activities
+
+	// Expose progress and order status as queries
 	progress, err := messages.SetQueryHandlerForProgress(ctx)
 	if err != nil {
 		return nil, err
 	}
+	status, err := messages.SetQueryHandlerForOrderStatus(ctx, input.OrderId, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow the order to be cancelled until shipping starts
+	ctx, cancellation, err := handleCancelOrder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create saga to manage order compensations, which also run when the order
+	// is cancelled
+	var saga app.Saga
+	defer func() {
+		if err != nil {
+			status.LastError = err.Error()
+			disconnectedCtx, _ := workflow.NewDisconnectedContext(ctx)
+			status.Compensations = saga.Compensate(disconnectedCtx)
+
+			if cancellation.Request != nil {
+				logger.Info("Order cancelled", "orderId", input.OrderId, "reason", cancellation.Request.Reason)
+				status.StartStep(disconnectedCtx, "Order Cancelled")
+				output = cancelledOrderOutput(input, cancellation)
+				err = nil
+			}
+		}
+	}()
 
 	// Get items
 	items := app.Items{}
-	err = workflow.ExecuteLocalActivity(laCtx, activities.GetItems).Get(ctx, &items)
+	err = workflow.ExecuteLocalActivity(laCtx, activities.GetItems, input).Get(ctx, &items)
 	if err != nil {
 		return nil, err
 	}
+	status.SetItems(items)
 
 	// Check fraud
+	status.StartStep(ctx, "Check Fraud")
 	err = workflow.ExecuteActivity(ctx, activities.CheckFraud, input).Get(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	// Reserve inventory
+	if useInventoryReservation(ctx) {
+		saga.AddCompensation(a.ReleaseInventory, input)
+		err = workflow.ExecuteActivity(ctx, a.ReserveInventory, input, items).Get(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	sleep(ctx, 1, progress, 25)
 
-	// Prepare shipment
-	err = workflow.ExecuteActivity(ctx, activities.PrepareShipment, input).Get(ctx, nil)
+	// Charge customer
+	status.StartStep(ctx, "Charge Customer")
+	payment := app.Payment{Amount: items.Total()}
+	saga.AddCompensation(a.UndoChargeCustomer, input, &payment)
+	err = workflow.ExecuteActivity(ctx, a.ChargeCustomer, input, payment.Amount, name).Get(ctx, &payment.AuthorizationId)
 	if err != nil {
 		return nil, err
 	}
 
 	sleep(ctx, 1, progress, 50)
 
-	// Charge customer
-	err = workflow.ExecuteActivity(ctx, activities.ChargeCustomer, input, name).Get(ctx, nil)
+	// Prepare shipment
+	status.StartStep(ctx, "Prepare Shipment")
+	saga.AddCompensation(activities.UndoPrepareShipment, input)
+	err = workflow.ExecuteActivity(ctx, activities.PrepareShipment, input).Get(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	sleep(ctx, 3, progress, 75)
 
-	// Ship order items
-	var shipFutures []workflow.Future
-	for _, item := range items {
-		logger.Info("Shipping item " + item.Description)
-		f := workflow.ExecuteActivity(ctx, activities.ShipOrder, input, item)
-		shipFutures = append(shipFutures, f)
+	// Stop if the order was cancelled while waiting
+	if err = ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	// Wait for all items to ship
-	for _, f := range shipFutures {
-		err = f.Get(ctx, nil)
+	// Ship order items, per warehouse. The order can no longer be cancelled.
+	cancellation.ShippingStarted = true
+	status.StartStep(ctx, "Ship Order")
+	var shipments []app.Shipment
+	if useSplitShipments(ctx) {
+		shipments, err = shipShipments(ctx, input, items, app.ShippingActivity, status)
 		if err != nil {
 			return nil, err
 		}
+	} else {
+		var shipFutures []workflow.Future
+		for i, item := range items {
+			logger.Info("Shipping item " + item.Description)
+			f := workflow.ExecuteActivity(withShipOrderOptions(ctx, input), activities.ShipOrder, app.ShippingInput{Order: input, Item: item})
+			shipFutures = append(shipFutures, f)
+			status.SetItemStatus(i, messages.ItemStatusShipping)
+		}
+
+		// Wait for all items to ship
+		err = awaitShipments(ctx, shipFutures, status)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Capture payment
+	err = workflow.ExecuteActivity(ctx, a.CapturePayment, input, payment).Get(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
+	payment.Captured = true
 
 	sleep(ctx, 0, progress, 100)
+	status.StartStep(ctx, "Order Completed")
 
-	// Generate trackingId
-	trackingId := uuid.New().String()
+	trackingIds := shipmentTrackingIds(shipments)
+	if len(shipments) == 0 {
+		// Generate trackingId for orders shipped item by item
+		trackingId := uuid.New().String()
+		status.TrackingIds = append(status.TrackingIds, trackingId)
+		trackingIds = []string{trackingId}
+	}
 	output = &app.OrderOutput{
-		TrackingId: trackingId,
-		Address:    input.Address,
+		TrackingId:  trackingIds[0],
+		TrackingIds: trackingIds,
+		Shipments:   shipments,
+		Address:     input.Address,
+		Status:      app.OrderStatusCompleted,
+		Items:       items,
+		Payment:     &payment,
 	}
 
 	return output, nil
@@ -103,3 +185,112 @@ func sleep(ctx workflow.Context, seconds int, progress *int, value int) {
 		workflow.Sleep(ctx, duration)
 	}
 }
+
+const (
+	shipOrderStartToCloseTimeout = 2 * time.Minute
+	shipOrderHeartbeatTimeout    = 5 * time.Second
+)
+
+// withShipOrderOptions returns a context for ShipOrder on the shipping
+// task queue of the order, or the order's own task queue if it has none. The carrier handoff takes longer than other activities and
+// heartbeats, so a lost worker is noticed within the heartbeat timeout and the
+// retry resumes from the last stage heartbeated.
+func withShipOrderOptions(ctx workflow.Context, input app.OrderInput) workflow.Context {
+	options := workflow.GetActivityOptions(ctx)
+	options.StartToCloseTimeout = shipOrderStartToCloseTimeout
+	options.HeartbeatTimeout = shipOrderHeartbeatTimeout
+	if input.ShippingTaskQueue != "" {
+		options.TaskQueue = input.ShippingTaskQueue
+	}
+	return workflow.WithActivityOptions(ctx, options)
+}
+
+// withCarrierConfirmationOptions gives the carrier a day to confirm delivery.
+// The carrier completes the activity, so it does not heartbeat.
+func withCarrierConfirmationOptions(ctx workflow.Context) workflow.Context {
+	options := workflow.GetActivityOptions(ctx)
+	options.StartToCloseTimeout = 24 * time.Hour
+	return workflow.WithActivityOptions(ctx, options)
+}
+
+// awaitShipments waits for the futures shipping each order item and records the
+// outcome of each item as it completes.
+func awaitShipments(ctx workflow.Context, shipFutures []workflow.Future, status *messages.OrderStatus) error {
+	var shipErr error
+	selector := workflow.NewSelector(ctx)
+	for i, f := range shipFutures {
+		selector.AddFuture(f, func(f workflow.Future) {
+			err := f.Get(ctx, nil)
+			if err != nil {
+				status.SetItemStatus(i, messages.ItemStatusFailed)
+				if shipErr == nil {
+					shipErr = err
+				}
+				return
+			}
+			status.SetItemStatus(i, messages.ItemStatusShipped)
+		})
+	}
+
+	for range shipFutures {
+		selector.Select(ctx)
+		if shipErr != nil {
+			return shipErr
+		}
+	}
+	return nil
+}
+
+// orderCancellation tracks whether an order can still be cancelled, and why
+// it was.
+type orderCancellation struct {
+	// Request is the cancellation of the order, nil unless it was cancelled
+	Request *messages.CancelOrderInput
+	// ShippingStarted is set once the order ships and can no longer be
+	// cancelled
+	ShippingStarted bool
+}
+
+// handleCancelOrder allows the order to be cancelled with the CancelOrder
+// update or signal until shipping starts. Cancelling the order cancels the
+// returned context, which interrupts whichever step is currently running.
+func handleCancelOrder(ctx workflow.Context) (workflow.Context, *orderCancellation, error) {
+	logger := workflow.GetLogger(ctx)
+	cancellation := &orderCancellation{}
+	ctx, cancelOrder := workflow.WithCancel(ctx)
+	canCancel := func() bool {
+		return !cancellation.ShippingStarted && cancellation.Request == nil
+	}
+	requestCancel := func(cancelInput messages.CancelOrderInput) {
+		cancellation.Request = &cancelInput
+		cancelOrder()
+	}
+	err := messages.SetUpdateHandlerForCancelOrder(ctx, canCancel, requestCancel)
+	if err != nil {
+		return nil, nil, err
+	}
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		c := messages.GetSignalChannelForCancelOrder(ctx)
+		for {
+			var cancelInput messages.CancelOrderInput
+			if more := c.Receive(ctx, &cancelInput); !more {
+				return
+			}
+			if !canCancel() {
+				logger.Info("Ignoring cancel signal, order is already shipping or cancelled")
+				continue
+			}
+			requestCancel(cancelInput)
+		}
+	})
+	return ctx, cancellation, nil
+}
+
+// cancelledOrderOutput returns the output of an order that was cancelled.
+func cancelledOrderOutput(input app.OrderInput, cancellation *orderCancellation) *app.OrderOutput {
+	return &app.OrderOutput{
+		Address: input.Address,
+		Status:  app.OrderStatusCancelled,
+		Reason:  cancellation.Request.Reason,
+	}
+}
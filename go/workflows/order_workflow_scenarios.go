@@ -14,13 +14,22 @@ import (
 	"go.temporal.io/sdk/workflow"
 )
 
+// Workflow types of the built-in scenarios, which are defined in
+// scenarios.yaml.
 const (
-	BUG        = "OrderWorkflowRecoverableFailure"
-	CHILD      = "OrderWorkflowChildWorkflow"
-	NEXUS      = "OrderWorkflowNexusOperation"
-	SIGNAL     = "OrderWorkflowHumanInLoopSignal"
-	UPDATE     = "OrderWorkflowHumanInLoopUpdate"
-	VISIBILITY = "OrderWorkflowAdvancedVisibility"
+	HAPPY          = "OrderWorkflowHappyPath"
+	BUG            = "OrderWorkflowRecoverableFailure"
+	CHILD          = "OrderWorkflowChildWorkflow"
+	NEXUS          = "OrderWorkflowNexusOperation"
+	SIGNAL         = "OrderWorkflowHumanInLoopSignal"
+	UPDATE         = "OrderWorkflowHumanInLoopUpdate"
+	VISIBILITY     = "OrderWorkflowAdvancedVisibility"
+	KEYROTATE      = "OrderWorkflowAPIKeyRotation"
+	APIFAILURE     = "OrderWorkflowAPIFailure"
+	NONRECOVERABLE = "OrderWorkflowNonRecoverableFailure"
+	WORKERCRASH    = "OrderWorkflowWorkerCrash"
+	CARRIER        = "OrderWorkflowCarrierConfirmation"
+	ENTITY         = "OrderWorkflowOrderEntity"
 )
 
 var orderStatusKey = temporal.NewSearchAttributeKeyKeyword("OrderStatus")
@@ -33,8 +42,17 @@ func OrderWorkflowScenarios(ctx workflow.Context, args converter.EncodedValues)
 	}
 
 	name := workflow.GetInfo(ctx).WorkflowType.Name
+	scenario, err := resolveScenario(ctx, name, &input)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withScenario(ctx, scenario)
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Dynamic Order workflow started", "type", name, "orderId", input.OrderId)
+	recordOrderStarted(ctx)
+	defer func() {
+		recordOrderResult(ctx, output, err)
+	}()
 
 	activityOptions := workflow.ActivityOptions{
 		StartToCloseTimeout: 5 * time.Second,
@@ -51,29 +69,52 @@ func OrderWorkflowScenarios(ctx workflow.Context, args converter.EncodedValues)
 	}
 	laCtx := workflow.WithLocalActivityOptions(ctx, localActivityOptions)
 
-	// Create saga to manage order compensations
-	var saga app.Saga
-	defer func() {
-		if err != nil {
-			disconnectedCtx, _ := workflow.NewDisconnectedContext(ctx)
-			saga.Compensate(disconnectedCtx)
-		}
-	}()
+	var a *activities.Activities
 
-	// Expose progress as query
+	// Expose progress and order status as queries
 	progress, err := messages.SetQueryHandlerForProgress(ctx)
 	if err != nil {
 		return nil, err
 	}
+	status, err := messages.SetQueryHandlerForOrderStatus(ctx, input.OrderId, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	// Allow the order to be cancelled until shipping starts
+	ctx, cancellation, err := handleCancelOrder(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create saga to manage order compensations. Compensations also run when the
+	// order is cancelled, either by the CancelOrder message or by a workflow
+	// cancellation request.
+	var saga app.Saga
+	defer func() {
+		if err != nil && !workflow.IsContinueAsNewError(err) {
+			status.LastError = err.Error()
+			disconnectedCtx, _ := workflow.NewDisconnectedContext(ctx)
+			status.Compensations = saga.Compensate(disconnectedCtx)
+
+			if cancellation.Request != nil {
+				logger.Info("Order cancelled", "orderId", input.OrderId, "reason", cancellation.Request.Reason)
+				updateProgress("Order Cancelled", status, progress, *progress, disconnectedCtx, 0)
+				output = cancelledOrderOutput(input, cancellation)
+				err = nil
+			}
+		}
+	}()
 
 	// Get items
 	items := app.Items{}
-	err = workflow.ExecuteLocalActivity(laCtx, activities.GetItems).Get(ctx, &items)
+	err = workflow.ExecuteLocalActivity(laCtx, activities.GetItems, input).Get(ctx, &items)
 	if err != nil {
 		return nil, err
 	}
+	status.SetItems(items)
 
-	updateProgress("Check Fraud", progress, 0, ctx, 0)
+	updateProgress("Check Fraud", status, progress, 0, ctx, 0)
 
 	// Check fraud
 	err = workflow.ExecuteActivity(ctx, activities.CheckFraud, input).Get(ctx, nil)
@@ -81,50 +122,74 @@ func OrderWorkflowScenarios(ctx workflow.Context, args converter.EncodedValues)
 		return nil, err
 	}
 
-	updateProgress("Prepare Shipment", progress, 25, ctx, 1)
+	// Reserve inventory
+	saga.AddCompensation(a.ReleaseInventory, input)
+	err = workflow.ExecuteActivity(ctx, a.ReserveInventory, input, items).Get(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
 
-	// Prepare shipment
-	saga.AddCompensation(activities.UndoPrepareShipment, input)
-	err = workflow.ExecuteActivity(ctx, activities.PrepareShipment, input).Get(ctx, nil)
+	updateProgress("Charge Customer", status, progress, 25, ctx, 1)
+
+	// Charge customer, authorizing the payment until the order has shipped.
+	// payment is passed by reference so the compensation sees how far payment
+	// got and can void or refund accordingly.
+	payment := app.Payment{Amount: items.Total()}
+	saga.AddCompensation(a.UndoChargeCustomer, input, &payment)
+	err = workflow.ExecuteActivity(ctx, a.ChargeCustomer, input, payment.Amount, name).Get(ctx, &payment.AuthorizationId)
 	if err != nil {
 		return nil, err
 	}
 
-	updateProgress("Charge Customer", progress, 50, ctx, 1)
+	updateProgress("Prepare Shipment", status, progress, 50, ctx, 1)
 
-	// Charge customer
-	saga.AddCompensation(activities.UndoChargeCustomer, input)
-	err = workflow.ExecuteActivity(ctx, activities.ChargeCustomer, input, name).Get(ctx, nil)
+	// Prepare shipment
+	saga.AddCompensation(activities.UndoPrepareShipment, input)
+	err = workflow.ExecuteActivity(ctx, activities.PrepareShipment, input).Get(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	updateProgress("Ship Order", progress, 75, ctx, 3)
+	if scenario.PickupDelay > 0 {
+		// Leave time to roll out the next worker version while the order is
+		// in flight
+		updateProgress("Awaiting Pickup", status, progress, 60, ctx, int(scenario.PickupDelay.Seconds()))
+		if useOrderWorkflowV2(ctx) {
+			updateProgress("Send Shipping Notice", status, progress, 70, ctx, 1)
+		}
+	}
 
-	if BUG == name {
-		// Simulate bug
-		panic("Simulated bug - fix me!")
+	updateProgress("Ship Order", status, progress, 75, ctx, 3)
+
+	if failure := scenario.FailsStep(app.StepWorkflow); failure != nil && !simulatedBugFixed(ctx) {
+		// Simulate bug, fixed by workers with PATCH_RECOVERABLE_FAILURE=true
+		panic(failure.Message)
 	}
 
-	if SIGNAL == name {
+	awaitTimeout := scenario.AwaitTimeout
+	if awaitTimeout == 0 {
+		awaitTimeout = time.Minute
+	}
+
+	if scenario.AwaitAddress == app.AwaitSignal {
 		// Await signal message to update address
-		logger.Info("Waiting up to 60 seconds for updated address")
+		logger.Info("Waiting for updated address", "timeout", awaitTimeout)
 		var updateInput messages.UpdateOrderInput
 		c := messages.GetSignalChannelForUpdateOrder(ctx)
-		ok, _ := c.ReceiveWithTimeout(ctx, time.Minute, &updateInput)
+		ok, _ := c.ReceiveWithTimeout(ctx, awaitTimeout, &updateInput)
 		if ok {
 			input.Address = updateInput.Address
 		}
 	}
 
-	if UPDATE == name {
+	if scenario.AwaitAddress == app.AwaitUpdate {
 		// Await update message to update address
-		logger.Info("Waiting up to 60 seconds for updated address")
+		logger.Info("Waiting for updated address", "timeout", awaitTimeout)
 		updatedAddress, err := messages.SetUpdateHandlerForUpdateOrder(ctx)
 		if err != nil {
 			return nil, err
 		}
-		ok, _ := workflow.AwaitWithTimeout(ctx, time.Minute, func() bool {
+		ok, _ := workflow.AwaitWithTimeout(ctx, awaitTimeout, func() bool {
 			return *updatedAddress != ""
 		})
 		if ok {
@@ -132,41 +197,101 @@ func OrderWorkflowScenarios(ctx workflow.Context, args converter.EncodedValues)
 		}
 	}
 
-	// Ship order items
-	var shipFutures []workflow.Future
-	for _, item := range items {
-		logger.Info("Shipping item " + item.Description)
-		shipFutures = append(shipFutures, shipItemAsync(ctx, input, item, name))
+	// Stop if the order was cancelled while waiting
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Ship order items, the order can no longer be cancelled
+	cancellation.ShippingStarted = true
+	var shipments []app.Shipment
+	if useSplitShipments(ctx) {
+		// Route the items to warehouses and ship each warehouse's shipment
+		shipments, err = shipShipments(ctx, input, items, scenario.Shipping, status)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var shipFutures []workflow.Future
+		for i, item := range items {
+			logger.Info("Shipping item " + item.Description)
+			shipFutures = append(shipFutures, shipItemAsync(ctx, input, item, scenario.Shipping))
+			status.SetItemStatus(i, messages.ItemStatusShipping)
+		}
+
+		// Wait for all items to ship
+		err = awaitShipments(ctx, shipFutures, status)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Wait for all items to ship
-	for _, f := range shipFutures {
-		err = f.Get(ctx, nil)
+	// Wait for the carrier to confirm delivery of each shipment, which
+	// completes the activities asynchronously
+	var deliveries []app.DeliveryConfirmation
+	if scenario.AwaitDelivery {
+		updateProgress("Awaiting Delivery", status, progress, 90, ctx, 0)
+		deliveryShipments := shipments
+		if len(deliveryShipments) == 0 {
+			// Items shipped one by one are delivered together
+			deliveryShipments = []app.Shipment{{Items: items}}
+		}
+		deliveries, err = awaitDeliveries(ctx, input, items, deliveryShipments, status)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	updateProgress("Order Completed", progress, 100, ctx, 0)
+	// Capture payment
+	err = workflow.ExecuteActivity(ctx, a.CapturePayment, input, payment).Get(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	payment.Captured = true
 
-	// Generate trackingId
-	trackingId := uuid.New().String()
+	updateProgress("Order Completed", status, progress, 100, ctx, 0)
+
+	trackingIds := shipmentTrackingIds(shipments)
+	if len(shipments) == 0 {
+		// Generate trackingId for orders shipped item by item
+		trackingId := uuid.New().String()
+		status.TrackingIds = append(status.TrackingIds, trackingId)
+		trackingIds = []string{trackingId}
+	}
 	output = &app.OrderOutput{
-		TrackingId: trackingId,
-		Address:    input.Address,
+		TrackingId:  trackingIds[0],
+		TrackingIds: trackingIds,
+		Shipments:   shipments,
+		Address:     input.Address,
+		Status:      app.OrderStatusCompleted,
+		Items:       items,
+		Payment:     &payment,
+		Deliveries:  deliveries,
+	}
+	if len(deliveries) > 0 {
+		output.Delivery = &deliveries[0]
+	}
+
+	if scenario.Entity {
+		// Hand the order over to its entity, which starts with a fresh history
+		status.Progress = *progress
+		return nil, workflow.NewContinueAsNewError(ctx, OrderEntityWorkflow, NewOrderEntityState(ctx, input, *output, *status))
 	}
 
 	return output, nil
 }
 
-func updateProgress(orderStatus string, progress *int, value int, ctx workflow.Context, seconds int) {
+func updateProgress(orderStatus string, status *messages.OrderStatus, progress *int, value int, ctx workflow.Context, seconds int) {
+	status.StartStep(ctx, orderStatus)
 	sleep(ctx, seconds, progress, value)
-	if VISIBILITY == workflow.GetInfo(ctx).WorkflowType.Name {
+	if scenarioFromContext(ctx).SearchAttributes {
 		workflow.UpsertTypedSearchAttributes(ctx, orderStatusKey.ValueSet(orderStatus))
 	}
 }
 
-func shipItemAsync(ctx workflow.Context, input app.OrderInput, item app.Item, name string) workflow.Future {
+// shipItemAsync ships item with an activity, a child workflow or a Nexus
+// operation, as set by the scenario's shipping.
+func shipItemAsync(ctx workflow.Context, input app.OrderInput, item app.Item, shipping string) workflow.Future {
 	logger := workflow.GetLogger(ctx)
 	var f workflow.Future
 
@@ -175,16 +300,17 @@ func shipItemAsync(ctx workflow.Context, input app.OrderInput, item app.Item, na
 		Item:  item,
 	}
 
-	if CHILD == name {
+	if app.ShippingChild == shipping {
 		// execute an async child wf to ship the item
 		cwo := workflow.ChildWorkflowOptions{
 			WorkflowID:        fmt.Sprintf("shipment-%v-%v", input.OrderId, item.Id),
+			TaskQueue:         input.ShippingTaskQueue,
 			ParentClosePolicy: enums.PARENT_CLOSE_POLICY_TERMINATE,
 		}
 		ctx = workflow.WithChildOptions(ctx, cwo)
 		f = workflow.ExecuteChildWorkflow(ctx, ShippingWorkflow, shippingInput)
 		logger.Info("Started Child Workflow: " + cwo.WorkflowID)
-	} else if NEXUS == name {
+	} else if app.ShippingNexus == shipping {
 		client := workflow.NewNexusClient(app.GetEnv("TEMPORAL_NEXUS_SHIPPING_ENDPOINT", "shipping-endpoint"), app.ShippingServiceName)
 
 		fut := client.ExecuteOperation(ctx, app.ShippingOperationName, shippingInput, workflow.NexusOperationOptions{})
@@ -195,7 +321,7 @@ func shipItemAsync(ctx workflow.Context, input app.OrderInput, item app.Item, na
 		logger.Info("Started Nexus Operation: " + exec.OperationToken)
 	} else {
 		// execute an async activity to ship the item
-		f = workflow.ExecuteActivity(ctx, activities.ShipOrder, shippingInput)
+		f = workflow.ExecuteActivity(withShipOrderOptions(ctx, input), activities.ShipOrder, shippingInput)
 		logger.Info("Started Activity: ShipOrder ")
 	}
 	return f
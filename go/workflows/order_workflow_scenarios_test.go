@@ -0,0 +1,443 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"temporal-order-management/activities"
+	"temporal-order-management/app"
+	"temporal-order-management/messages"
+	"testing"
+	"time"
+
+	"github.com/nexus-rpc/sdk-go/nexus"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+type OrderWorkflowScenariosTestSuite struct {
+	suite.Suite
+	testsuite.WorkflowTestSuite
+
+	env     *testsuite.TestWorkflowEnvironment
+	started []string
+}
+
+func TestOrderWorkflowScenarios(t *testing.T) {
+	suite.Run(t, new(OrderWorkflowScenariosTestSuite))
+}
+
+func (s *OrderWorkflowScenariosTestSuite) SetupTest() {
+	s.env = newTestEnvironment(&s.WorkflowTestSuite, nil)
+
+	s.started = nil
+	s.env.SetOnActivityStartedListener(func(info *activity.Info, ctx context.Context, args converter.EncodedValues) {
+		s.started = append(s.started, info.ActivityType.Name)
+	})
+}
+
+func (s *OrderWorkflowScenariosTestSuite) AfterTest(suiteName, testName string) {
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *OrderWorkflowScenariosTestSuite) executeScenario(workflowType string) *app.OrderOutput {
+	s.env.ExecuteWorkflow(workflowType, testOrder())
+	s.True(s.env.IsWorkflowCompleted())
+	if s.env.GetWorkflowError() != nil {
+		return nil
+	}
+
+	var output app.OrderOutput
+	s.NoError(s.env.GetWorkflowResult(&output))
+	return &output
+}
+
+func (s *OrderWorkflowScenariosTestSuite) orderStatus() messages.OrderStatus {
+	value, err := s.env.QueryWorkflow("getOrderStatus")
+	s.NoError(err)
+	var status messages.OrderStatus
+	s.NoError(value.Get(&status))
+	return status
+}
+
+func (s *OrderWorkflowScenariosTestSuite) compensations() []string {
+	var undone []string
+	for _, name := range s.started {
+		switch name {
+		case "UndoPrepareShipment", "UndoChargeCustomer", "ReleaseInventory":
+			undone = append(undone, name)
+		}
+	}
+	return undone
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_APIFailure_RetriesCharge() {
+	var a *activities.Activities
+	s.env.OnActivity(a.ChargeCustomer, mock.Anything, mock.Anything, mock.Anything, APIFAILURE).
+		Return("", errors.New("charge customer activity failed, API unavailable")).Times(4)
+	mockActivities(s.env)
+
+	output := s.executeScenario(APIFAILURE)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(app.OrderStatusCompleted, output.Status)
+	s.NotEmpty(output.TrackingId)
+	s.env.AssertActivityNumberOfCalls(s.T(), "ChargeCustomer", 5)
+	s.Empty(s.compensations())
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_NonRecoverableFailure_CompensatesInReverseOrder() {
+	var a *activities.Activities
+	s.env.OnActivity(a.ChargeCustomer, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("", temporal.NewNonRetryableApplicationError("charge customer activity failed", "activityFailure", errors.New("credit card invalid")))
+	mockActivities(s.env)
+
+	s.executeScenario(NONRECOVERABLE)
+
+	var appErr *temporal.ApplicationError
+	s.True(errors.As(s.env.GetWorkflowError(), &appErr))
+	s.Equal("activityFailure", appErr.Type())
+	s.env.AssertActivityNumberOfCalls(s.T(), "ChargeCustomer", 1)
+	s.env.AssertActivityNotCalled(s.T(), "PrepareShipment", mock.Anything, mock.Anything)
+	s.Equal([]string{"UndoChargeCustomer", "ReleaseInventory"}, s.compensations())
+
+	status := s.orderStatus()
+	s.Equal("Charge Customer", status.CurrentStep)
+	s.Len(status.Compensations, 2)
+	s.Equal("UndoChargeCustomer", status.Compensations[0].Activity)
+	s.Contains(status.LastError, "credit card invalid")
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_CarrierConfirmation_AwaitsDeliveryOfEachShipment() {
+	var a *activities.Activities
+	deliveredAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	east := app.DeliveryConfirmation{ShipmentId: "123456-east", DeliveredAt: deliveredAt, ReceivedBy: "J. Doe"}
+	west := app.DeliveryConfirmation{ShipmentId: "123456-west", DeliveredAt: deliveredAt.Add(6 * time.Hour)}
+	shipment := func(id string) any {
+		return mock.MatchedBy(func(input app.ShippingInput) bool { return input.Shipment.Id == id })
+	}
+	s.env.OnActivity(a.AwaitCarrierConfirmation, mock.Anything, shipment("east")).
+		After(6*time.Hour).Return(east, nil).Once()
+	s.env.OnActivity(a.AwaitCarrierConfirmation, mock.Anything, shipment("west")).
+		After(12*time.Hour).Return(west, nil).Once()
+	mockActivities(s.env)
+	// only the items of the delivered shipment are delivered
+	var itemStatuses []string
+	s.env.RegisterDelayedCallback(func() {
+		for _, item := range s.orderStatus().Items {
+			itemStatuses = append(itemStatuses, item.Status)
+		}
+	}, 9*time.Hour)
+
+	output := s.executeScenario(CARRIER)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal([]string{messages.ItemStatusDelivered, messages.ItemStatusShipped}, itemStatuses)
+	s.Equal([]app.DeliveryConfirmation{east, west}, output.Deliveries)
+	s.Equal(&east, output.Delivery)
+	s.Equal("CapturePayment", s.started[len(s.started)-1])
+	for _, item := range s.orderStatus().Items {
+		s.Equal(messages.ItemStatusDelivered, item.Status)
+	}
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_CarrierConfirmation_FailedDeliveryCompensates() {
+	var a *activities.Activities
+	s.env.OnActivity(a.AwaitCarrierConfirmation, mock.Anything, mock.Anything).
+		Return(app.DeliveryConfirmation{}, temporal.NewNonRetryableApplicationError("address not found", activities.DeliveryFailed, nil)).Once()
+	mockActivities(s.env)
+
+	s.executeScenario(CARRIER)
+
+	var appErr *temporal.ApplicationError
+	s.True(errors.As(s.env.GetWorkflowError(), &appErr))
+	s.Equal(activities.DeliveryFailed, appErr.Type())
+	s.Equal([]string{"UndoPrepareShipment", "UndoChargeCustomer", "ReleaseInventory"}, s.compensations())
+	s.env.AssertActivityNotCalled(s.T(), "CapturePayment", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_OrderEntity_ContinuesAsNewAsEntity() {
+	mockActivities(s.env)
+
+	s.executeScenario(ENTITY)
+
+	var canErr *workflow.ContinueAsNewError
+	s.True(errors.As(s.env.GetWorkflowError(), &canErr))
+	s.Equal("OrderEntityWorkflow", canErr.WorkflowType.Name)
+	var state OrderEntityState
+	s.NoError(converter.GetDefaultDataConverter().FromPayloads(canErr.Input, &state))
+	s.Equal(app.OrderStatusCompleted, state.Output.Status)
+	s.Equal("Order Completed", state.Status.CurrentStep)
+	s.Equal(100, state.Status.Progress)
+	s.Equal(testOrder().Address, state.Status.Address)
+	s.Empty(s.compensations())
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_ShippingFailure_CompensatesEveryStep() {
+	s.env.OnActivity(activities.ShipOrder, mock.Anything, mock.Anything).
+		Return(temporal.NewNonRetryableApplicationError("carrier rejected shipment", "shippingFailure", nil))
+	mockActivities(s.env)
+
+	s.executeScenario(HAPPY)
+
+	s.Error(s.env.GetWorkflowError())
+	s.Equal([]string{"UndoPrepareShipment", "UndoChargeCustomer", "ReleaseInventory"}, s.compensations())
+	s.env.AssertActivityNotCalled(s.T(), "CapturePayment", mock.Anything, mock.Anything, mock.Anything)
+	s.Equal(messages.ItemStatusFailed, s.orderStatus().Items[0].Status)
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_OutOfStock() {
+	var a *activities.Activities
+	outOfStock := activities.OutOfStockError{Sku: "KEYPAD", Requested: 1, Available: 0}
+	s.env.OnActivity(a.ReserveInventory, mock.Anything, mock.Anything, mock.Anything).
+		Return("", temporal.NewNonRetryableApplicationError(outOfStock.Error(), activities.OutOfStockErrorType, nil, outOfStock))
+	mockActivities(s.env)
+
+	s.executeScenario(HAPPY)
+
+	var appErr *temporal.ApplicationError
+	s.True(errors.As(s.env.GetWorkflowError(), &appErr))
+	s.Equal(activities.OutOfStockErrorType, appErr.Type())
+	var details activities.OutOfStockError
+	s.NoError(appErr.Details(&details))
+	s.Equal("KEYPAD", details.Sku)
+	s.env.AssertActivityNotCalled(s.T(), "ChargeCustomer", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	s.Equal([]string{"ReleaseInventory"}, s.compensations())
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_RecoverableFailure_Panics() {
+	mockActivities(s.env)
+
+	s.executeScenario(BUG)
+
+	var panicErr *temporal.PanicError
+	s.True(errors.As(s.env.GetWorkflowError(), &panicErr))
+	s.Contains(panicErr.Error(), "Simulated bug - fix me!")
+	s.env.AssertActivityNotCalled(s.T(), "ShipOrder", mock.Anything, mock.Anything)
+	s.Empty(s.compensations())
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_RecoverableFailure_Patched() {
+	PatchRecoverableFailure = true
+	defer func() { PatchRecoverableFailure = false }()
+	mockActivities(s.env)
+
+	output := s.executeScenario(BUG)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(app.OrderStatusCompleted, output.Status)
+	s.Empty(s.compensations())
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_HumanInLoopSignal_UpdatesAddress() {
+	mockActivities(s.env)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("UpdateOrder", messages.UpdateOrderInput{Address: "456 Oak Ave"})
+	}, 10*time.Second)
+
+	output := s.executeScenario(SIGNAL)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal("456 Oak Ave", output.Address)
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_HumanInLoopSignal_TimesOut() {
+	mockActivities(s.env)
+
+	start := s.env.Now()
+	output := s.executeScenario(SIGNAL)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(testOrder().Address, output.Address)
+	s.GreaterOrEqual(s.env.Now().Sub(start), time.Minute)
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_HumanInLoopUpdate_UpdatesAddress() {
+	mockActivities(s.env)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflowNoRejection("UpdateOrder", "update-1", s.T(), messages.UpdateOrderInput{Address: "456 Oak Ave"})
+	}, 10*time.Second)
+
+	output := s.executeScenario(UPDATE)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal("456 Oak Ave", output.Address)
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_HumanInLoopUpdate_RejectsInvalidAddressAndTimesOut() {
+	mockActivities(s.env)
+	rejected := false
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflow("UpdateOrder", "update-1", &testsuite.TestUpdateCallback{
+			OnAccept:   func() { s.Fail("update should be rejected") },
+			OnReject:   func(err error) { rejected = true },
+			OnComplete: func(any, error) {},
+		}, messages.UpdateOrderInput{Address: "Oak Ave"})
+	}, 10*time.Second)
+
+	output := s.executeScenario(UPDATE)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.True(rejected)
+	s.Equal(testOrder().Address, output.Address)
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_AdvancedVisibility_UpsertsOrderStatus() {
+	mockActivities(s.env)
+	for _, orderStatus := range []string{"Check Fraud", "Charge Customer", "Prepare Shipment", "Ship Order", "Order Completed"} {
+		s.env.OnUpsertTypedSearchAttributes(temporal.NewSearchAttributes(orderStatusKey.ValueSet(orderStatus))).Return(nil).Once()
+	}
+
+	output := s.executeScenario(VISIBILITY)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(app.OrderStatusCompleted, output.Status)
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_ChildWorkflow_ShipsEachShipment() {
+	mockActivities(s.env)
+	s.env.OnWorkflow(ShippingWorkflow, mock.Anything, mock.Anything).Return("tracking-1", nil)
+	var childIds []string
+	s.env.SetOnChildWorkflowStartedListener(func(info *workflow.Info, _ workflow.Context, _ converter.EncodedValues) {
+		childIds = append(childIds, info.WorkflowExecution.ID)
+	})
+
+	output := s.executeScenario(CHILD)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(app.OrderStatusCompleted, output.Status)
+	s.ElementsMatch([]string{"shipment-123456-east", "shipment-123456-west"}, childIds)
+	s.Len(output.TrackingIds, 2)
+	s.env.AssertWorkflowNumberOfCalls(s.T(), "ShippingWorkflow", 2)
+	s.env.AssertActivityNotCalled(s.T(), "ShipOrder", mock.Anything, mock.Anything)
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_NexusOperation_ShipsEachShipment() {
+	mockActivities(s.env)
+	operation := nexus.NewOperationReference[app.ShippingInput, string](app.ShippingOperationName)
+	s.env.OnNexusOperation(app.ShippingServiceName, operation, mock.Anything, mock.Anything).
+		Return(&nexus.HandlerStartOperationResultSync[string]{Value: ""}, nil)
+
+	output := s.executeScenario(NEXUS)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(app.OrderStatusCompleted, output.Status)
+	s.env.AssertNexusOperationNumberOfCalls(s.T(), app.ShippingServiceName, 2)
+	s.env.AssertActivityNotCalled(s.T(), "ShipOrder", mock.Anything, mock.Anything)
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_CancelOrderUpdate_CompensatesAndReturnsCancelled() {
+	mockActivities(s.env)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflowNoRejection("CancelOrder", "cancel-1", s.T(), messages.CancelOrderInput{Reason: "changed my mind"})
+	}, 1500*time.Millisecond)
+
+	output := s.executeScenario(HAPPY)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal(app.OrderStatusCancelled, output.Status)
+	s.Equal("changed my mind", output.Reason)
+	s.env.AssertActivityNotCalled(s.T(), "ShipOrder", mock.Anything, mock.Anything)
+	s.Equal([]string{"UndoPrepareShipment", "UndoChargeCustomer", "ReleaseInventory"}, s.compensations())
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_CancelOrderUpdate_RejectedOnceShipping() {
+	mockActivities(s.env, func(env *testsuite.TestWorkflowEnvironment) {
+		env.OnActivity(activities.ShipOrder, mock.Anything, mock.Anything).After(10 * time.Second).Return(nil)
+	})
+	rejected := false
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflow("CancelOrder", "cancel-1", &testsuite.TestUpdateCallback{
+			OnAccept:   func() { s.Fail("cancellation should be rejected") },
+			OnReject:   func(err error) { rejected = true },
+			OnComplete: func(any, error) {},
+		}, messages.CancelOrderInput{Reason: "too late"})
+	}, 8*time.Second)
+
+	output := s.executeScenario(HAPPY)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.True(rejected)
+	s.Equal(app.OrderStatusCompleted, output.Status)
+}
+
+func (s *OrderWorkflowScenariosTestSuite) Test_WorkflowCancellation_Compensates() {
+	mockActivities(s.env)
+	s.env.RegisterDelayedCallback(s.env.CancelWorkflow, 1500*time.Millisecond)
+
+	s.executeScenario(HAPPY)
+
+	s.True(temporal.IsCanceledError(s.env.GetWorkflowError()))
+	s.Equal([]string{"UndoPrepareShipment", "UndoChargeCustomer", "ReleaseInventory"}, s.compensations())
+}
+
+func testOrder() app.OrderInput {
+	return app.OrderInput{
+		OrderId:  "123456",
+		Address:  "123 Main St. Redwood, CA",
+		Customer: app.Customer{Name: "Alice Jones"},
+		Items: []app.LineItem{
+			{Sku: "TBL-TOP", Quantity: 1},
+			{Sku: "TBL-LEGS", Quantity: 2},
+		},
+		PaymentRef: "tok_visa",
+	}
+}
+
+func testItems() app.Items {
+	return app.Items{
+		{Id: 654300, Sku: "TBL-TOP", Description: "Table Top", Quantity: 1, UnitPrice: 12900},
+		{Id: 654321, Sku: "TBL-LEGS", Description: "Table Legs", Quantity: 2, UnitPrice: 2450},
+	}
+}
+
+// newTestEnvironment creates an environment from ts with the workflows and
+// activities of the workers registered. The Activities methods run against a,
+// or against no dependencies if a is nil, for tests that mock them.
+func newTestEnvironment(ts *testsuite.WorkflowTestSuite, a *activities.Activities) *testsuite.TestWorkflowEnvironment {
+	env := ts.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(OrderWorkflow)
+	env.RegisterDynamicWorkflow(OrderWorkflowScenarios, workflow.DynamicRegisterOptions{})
+	env.RegisterWorkflow(ShippingWorkflow)
+	env.RegisterWorkflow(ReturnWorkflow)
+	env.RegisterWorkflow(OrderEntityWorkflow)
+
+	if a == nil {
+		a = &activities.Activities{}
+	}
+	env.RegisterActivity(activities.GetItems)
+	env.RegisterActivity(activities.CheckFraud)
+	env.RegisterActivity(activities.PrepareShipment)
+	env.RegisterActivity(activities.UndoPrepareShipment)
+	env.RegisterActivity(activities.ShipOrder)
+	env.RegisterActivity(activities.InspectReturn)
+	env.RegisterActivity(a)
+	return env
+}
+
+// mockActivities mocks every order activity to succeed, without expecting any
+// of them to be called. Mocks registered before calling it, or by the
+// overrides, take precedence.
+func mockActivities(env *testsuite.TestWorkflowEnvironment, overrides ...func(*testsuite.TestWorkflowEnvironment)) {
+	for _, override := range overrides {
+		override(env)
+	}
+
+	var a *activities.Activities
+	env.OnActivity(activities.GetItems, mock.Anything, mock.Anything).Return(testItems(), nil).Maybe()
+	env.OnActivity(activities.CheckFraud, mock.Anything, mock.Anything).Return("", nil).Maybe()
+	env.OnActivity(a.ReserveInventory, mock.Anything, mock.Anything, mock.Anything).Return("", nil).Maybe()
+	env.OnActivity(a.ChargeCustomer, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return("auth-1", nil).Maybe()
+	env.OnActivity(activities.PrepareShipment, mock.Anything, mock.Anything).Return("", nil).Maybe()
+	env.OnActivity(activities.ShipOrder, mock.Anything, mock.Anything).Return(nil).Maybe()
+	env.OnActivity(a.CapturePayment, mock.Anything, mock.Anything, mock.Anything).Return("", nil).Maybe()
+	env.OnActivity(a.AwaitCarrierConfirmation, mock.Anything, mock.Anything).Return(app.DeliveryConfirmation{}, nil).Maybe()
+
+	env.OnActivity(activities.UndoPrepareShipment, mock.Anything, mock.Anything).Return("", nil).Maybe()
+	env.OnActivity(a.UndoChargeCustomer, mock.Anything, mock.Anything, mock.Anything).Return("", nil).Maybe()
+	env.OnActivity(a.ReleaseInventory, mock.Anything, mock.Anything).Return("", nil).Maybe()
+}
@@ -0,0 +1,162 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"temporal-order-management/activities"
+	"temporal-order-management/app"
+	"temporal-order-management/messages"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+type OrderWorkflowTestSuite struct {
+	suite.Suite
+	testsuite.WorkflowTestSuite
+
+	env *testsuite.TestWorkflowEnvironment
+}
+
+func TestOrderWorkflow(t *testing.T) {
+	suite.Run(t, new(OrderWorkflowTestSuite))
+}
+
+func (s *OrderWorkflowTestSuite) SetupTest() {
+	s.env = newTestEnvironment(&s.WorkflowTestSuite, nil)
+}
+
+func (s *OrderWorkflowTestSuite) AfterTest(suiteName, testName string) {
+	s.env.AssertExpectations(s.T())
+}
+
+func (s *OrderWorkflowTestSuite) Test_ShipsOrderedItemsAndCapturesPayment() {
+	var a *activities.Activities
+	order := testOrder()
+	total := testItems().Total()
+	s.env.OnActivity(a.ReserveInventory, mock.Anything, order, testItems()).Return("", nil).Once()
+	s.env.OnActivity(a.ChargeCustomer, mock.Anything, order, total, "OrderWorkflow").Return("auth-1", nil).Once()
+	for _, item := range testItems() {
+		s.env.OnActivity(activities.ShipOrder, mock.Anything, app.ShippingInput{Order: order, Item: item}).Return(nil).Once()
+	}
+	s.env.OnActivity(a.CapturePayment, mock.Anything, order, app.Payment{AuthorizationId: "auth-1", Amount: total}).Return("", nil).Once()
+	mockActivities(s.env)
+
+	s.env.ExecuteWorkflow(OrderWorkflow, order)
+
+	s.True(s.env.IsWorkflowCompleted())
+	s.NoError(s.env.GetWorkflowError())
+	var output app.OrderOutput
+	s.NoError(s.env.GetWorkflowResult(&output))
+	s.Equal(app.OrderStatusCompleted, output.Status)
+	s.Equal(order.Address, output.Address)
+	// the default warehouses split the order in two
+	s.Len(output.Shipments, 2)
+	s.Equal("east", output.Shipments[0].Id)
+	s.Equal(testItems()[:1], output.Shipments[0].Items)
+	s.Equal("west", output.Shipments[1].Id)
+	s.Equal(testItems()[1:], output.Shipments[1].Items)
+	s.Len(output.TrackingIds, 2)
+	s.Equal(output.TrackingIds[0], output.TrackingId)
+
+	value, err := s.env.QueryWorkflow("getProgress")
+	s.NoError(err)
+	var progress int
+	s.NoError(value.Get(&progress))
+	s.Equal(100, progress)
+
+	value, err = s.env.QueryWorkflow("getOrderStatus")
+	s.NoError(err)
+	var status messages.OrderStatus
+	s.NoError(value.Get(&status))
+	s.Equal("Order Completed", status.CurrentStep)
+	s.Len(status.CompletedSteps, 4)
+	s.ElementsMatch(output.TrackingIds, status.TrackingIds)
+	for _, item := range status.Items {
+		s.Equal(messages.ItemStatusShipped, item.Status)
+	}
+}
+
+func (s *OrderWorkflowTestSuite) Test_InvalidOrderFails() {
+	s.env.OnActivity(activities.GetItems, mock.Anything, mock.Anything).
+		Return(nil, temporal.NewNonRetryableApplicationError("unknown sku NOPE", "invalidOrder", nil))
+	mockActivities(s.env)
+
+	s.env.ExecuteWorkflow(OrderWorkflow, testOrder())
+
+	var appErr *temporal.ApplicationError
+	s.True(errors.As(s.env.GetWorkflowError(), &appErr))
+	s.Equal("invalidOrder", appErr.Type())
+	s.env.AssertActivityNotCalled(s.T(), "CheckFraud", mock.Anything, mock.Anything)
+}
+
+func (s *OrderWorkflowTestSuite) Test_ShipsOnShippingTaskQueue() {
+	mockActivities(s.env)
+	taskQueues := map[string]string{}
+	s.env.SetOnActivityStartedListener(func(info *activity.Info, _ context.Context, _ converter.EncodedValues) {
+		taskQueues[info.ActivityType.Name] = info.TaskQueue
+	})
+	order := testOrder()
+	order.ShippingTaskQueue = "shipping"
+
+	s.env.ExecuteWorkflow(OrderWorkflow, order)
+
+	s.NoError(s.env.GetWorkflowError())
+	s.Equal("shipping", taskQueues["ShipOrder"])
+	s.NotEqual("shipping", taskQueues["CheckFraud"])
+}
+
+func (s *OrderWorkflowTestSuite) orderStatus() messages.OrderStatus {
+	value, err := s.env.QueryWorkflow("getOrderStatus")
+	s.NoError(err)
+	var status messages.OrderStatus
+	s.NoError(value.Get(&status))
+	return status
+}
+
+func (s *OrderWorkflowTestSuite) Test_CancelOrderUpdate_CompensatesAndReturnsCancelled() {
+	mockActivities(s.env)
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflowNoRejection("CancelOrder", "cancel-1", s.T(), messages.CancelOrderInput{Reason: "changed my mind"})
+	}, 1500*time.Millisecond)
+
+	s.env.ExecuteWorkflow(OrderWorkflow, testOrder())
+
+	s.NoError(s.env.GetWorkflowError())
+	var output app.OrderOutput
+	s.NoError(s.env.GetWorkflowResult(&output))
+	s.Equal(app.OrderStatusCancelled, output.Status)
+	s.Equal("changed my mind", output.Reason)
+	s.env.AssertActivityNotCalled(s.T(), "ShipOrder", mock.Anything, mock.Anything)
+
+	status := s.orderStatus()
+	s.Equal("Order Cancelled", status.CurrentStep)
+	var undone []string
+	for _, compensation := range status.Compensations {
+		undone = append(undone, compensation.Activity)
+	}
+	s.Equal([]string{"UndoPrepareShipment", "UndoChargeCustomer", "ReleaseInventory"}, undone)
+}
+
+func (s *OrderWorkflowTestSuite) Test_CancelOrderSignal_IgnoredOnceShipping() {
+	mockActivities(s.env, func(env *testsuite.TestWorkflowEnvironment) {
+		env.OnActivity(activities.ShipOrder, mock.Anything, mock.Anything).After(10 * time.Second).Return(nil)
+	})
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow("CancelOrder", messages.CancelOrderInput{Reason: "too late"})
+	}, 8*time.Second)
+
+	s.env.ExecuteWorkflow(OrderWorkflow, testOrder())
+
+	s.NoError(s.env.GetWorkflowError())
+	var output app.OrderOutput
+	s.NoError(s.env.GetWorkflowResult(&output))
+	s.Equal(app.OrderStatusCompleted, output.Status)
+	s.Empty(s.orderStatus().Compensations)
+}
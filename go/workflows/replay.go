@@ -0,0 +1,60 @@
+package workflows
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"temporal-order-management/app"
+
+	"go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+)
+
+// NewWorkflowReplayer returns a replayer with the workers' workflows registered
+// the same way the workers register them. Histories of encrypted orders need
+// the keyring in TEMPORAL_CODEC_KEYRING to replay.
+func NewWorkflowReplayer() worker.WorkflowReplayer {
+	replayer, err := worker.NewWorkflowReplayerWithOptions(worker.WorkflowReplayerOptions{
+		DataConverter: app.GetDataConverter(),
+	})
+	if err != nil {
+		panic(err)
+	}
+	replayer.RegisterWorkflowWithOptions(OrderWorkflow, workflow.RegisterOptions{
+		Name: HAPPY,
+	})
+	replayer.RegisterDynamicWorkflow(OrderWorkflowScenarios, workflow.DynamicRegisterOptions{})
+	replayer.RegisterWorkflow(ShippingWorkflow)
+	replayer.RegisterWorkflow(ReturnWorkflow)
+	replayer.RegisterWorkflow(OrderEntityWorkflow)
+	return replayer
+}
+
+// ReplayResult is the outcome of replaying a single history file. Err is set
+// when the workflow code is no longer compatible with the history.
+type ReplayResult struct {
+	File string
+	Err  error
+}
+
+// ReplayHistories replays every JSON history in dir against the current
+// workflow code, returning one result per file in file name order.
+func ReplayHistories(dir string, logger log.Logger) ([]ReplayResult, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+
+	var results []ReplayResult
+	for _, file := range files {
+		replayer := NewWorkflowReplayer()
+		err := replayer.ReplayWorkflowHistoryFromJSONFile(logger, file)
+		results = append(results, ReplayResult{File: file, Err: err})
+	}
+	return results, nil
+}
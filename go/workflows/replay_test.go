@@ -0,0 +1,67 @@
+package workflows
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestReplayHistories replays the histories in testdata/histories to catch
+// changes that would break in-flight orders. Add a history with
+// go run ./cmd/histories export -workflow-id <id> -dir workflows/testdata/histories
+func TestReplayHistories(t *testing.T) {
+	// replay as the patched workers do, histories from before the patch must
+	// still replay
+	patchRecoverableFailure(t, true)
+
+	results, err := ReplayHistories(filepath.Join("testdata", "histories"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) == 0 {
+		t.Skip("no histories in testdata/histories")
+	}
+
+	for _, result := range results {
+		t.Run(filepath.Base(result.File), func(t *testing.T) {
+			if result.Err != nil {
+				t.Errorf("replay of %v failed: %v", result.File, result.Err)
+			}
+		})
+	}
+}
+
+// TestReplayRecoverableFailurePatch replays an order stuck on the simulated
+// bug, and one that completed after the patch was deployed, with and without
+// the patch.
+func TestReplayRecoverableFailurePatch(t *testing.T) {
+	stuck := filepath.Join("testdata", "histories", "OrderWorkflowRecoverableFailureStuck.json")
+	patched := filepath.Join("testdata", "histories", "OrderWorkflowRecoverableFailurePatched.json")
+
+	tests := []struct {
+		file    string
+		patch   bool
+		wantErr bool
+	}{
+		{file: stuck, patch: false},
+		{file: stuck, patch: true},
+		{file: patched, patch: true},
+		// the patched history needs the patched code
+		{file: patched, patch: false, wantErr: true},
+	}
+	for _, tt := range tests {
+		patchRecoverableFailure(t, tt.patch)
+		err := NewWorkflowReplayer().ReplayWorkflowHistoryFromJSONFile(nil, tt.file)
+		if tt.wantErr && err == nil {
+			t.Errorf("replay of %v with patch %v succeeded, expected it to fail", filepath.Base(tt.file), tt.patch)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("replay of %v with patch %v failed: %v", filepath.Base(tt.file), tt.patch, err)
+		}
+	}
+}
+
+func patchRecoverableFailure(t *testing.T, patch bool) {
+	previous := PatchRecoverableFailure
+	PatchRecoverableFailure = patch
+	t.Cleanup(func() { PatchRecoverableFailure = previous })
+}
@@ -0,0 +1,96 @@
+package workflows
+
+import (
+	"temporal-order-management/activities"
+	"temporal-order-management/app"
+	"temporal-order-management/messages"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// ReturnWindow is how long the customer has to send a returned item back.
+var ReturnWindow = 30 * 24 * time.Hour
+
+// ReturnWorkflow returns an item of a completed order: it waits for the item to
+// be received, inspects it, restocks it and refunds part of the payment. If
+// the refund fails the item is taken out of stock again.
+func ReturnWorkflow(ctx workflow.Context, input app.ReturnInput) (output *app.ReturnOutput, err error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Return started", "orderId", input.Shipment.Order.OrderId, "itemId", input.Shipment.Item.Id)
+
+	activityOptions := workflow.ActivityOptions{
+		StartToCloseTimeout: 5 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    1 * time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    30 * time.Second,
+		},
+	}
+	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+
+	var a *activities.Activities
+
+	// Expose the return status as a query
+	status, err := messages.SetQueryHandlerForReturnStatus(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var saga app.Saga
+	defer func() {
+		if err != nil {
+			status.Status = messages.ReturnStatusFailed
+			status.LastError = err.Error()
+			disconnectedCtx, _ := workflow.NewDisconnectedContext(ctx)
+			status.Compensations = saga.Compensate(disconnectedCtx)
+		}
+	}()
+
+	// Wait for the item to arrive at the warehouse
+	var received messages.ReturnReceivedInput
+	ok, _ := messages.GetSignalChannelForReturnReceived(ctx).ReceiveWithTimeout(ctx, ReturnWindow, &received)
+	if !ok {
+		logger.Info("Return window closed before the item was received")
+		status.Status = messages.ReturnStatusExpired
+		return &app.ReturnOutput{Status: status.Status}, nil
+	}
+
+	// Inspect the item
+	status.Status = messages.ReturnStatusInspecting
+	var inspection app.Inspection
+	err = workflow.ExecuteActivity(ctx, activities.InspectReturn, input, received.Condition).Get(ctx, &inspection)
+	if err != nil {
+		return nil, err
+	}
+	status.Condition = inspection.Condition
+
+	// Restock the item
+	if inspection.Restock {
+		saga.AddCompensation(a.UndoRestockItem, input)
+		err = workflow.ExecuteActivity(ctx, a.RestockItem, input).Get(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		status.Restocked = true
+	}
+
+	// Refund the customer
+	status.Status = messages.ReturnStatusRejected
+	if inspection.RefundAmount > 0 {
+		err = workflow.ExecuteActivity(ctx, a.RefundCustomer, input, inspection.RefundAmount).Get(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		status.RefundAmount = inspection.RefundAmount
+		status.Status = messages.ReturnStatusRefunded
+	}
+
+	return &app.ReturnOutput{
+		Status:       status.Status,
+		Condition:    status.Condition,
+		RefundAmount: status.RefundAmount,
+		Restocked:    status.Restocked,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package workflows
+
+import (
+	"context"
+	"errors"
+	"temporal-order-management/activities"
+	"temporal-order-management/app"
+	"temporal-order-management/messages"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// newReturnTestEnvironment runs the return activities against an in-memory
+// inventory and payment gateway, with a captured payment for the test order.
+func newReturnTestEnvironment(t *testing.T) (*testsuite.TestWorkflowEnvironment, app.ReturnInput, *activities.MemoryInventory, *activities.MemoryPaymentGateway) {
+	ctx := context.Background()
+	payments := activities.NewMemoryPaymentGateway()
+	items := testItems()
+	authorizationId, err := payments.Authorize(ctx, testOrder().OrderId, "tok_visa", items.Total())
+	require.NoError(t, err)
+	require.NoError(t, payments.Capture(ctx, authorizationId, items.Total()))
+	inventory := activities.NewMemoryInventory(map[string]int{"TBL-LEGS": 10})
+
+	var s testsuite.WorkflowTestSuite
+	env := newTestEnvironment(&s, &activities.Activities{Payments: payments, Inventory: inventory})
+
+	order := app.OrderOutput{
+		Status:  app.OrderStatusCompleted,
+		Items:   items,
+		Payment: &app.Payment{AuthorizationId: authorizationId, Amount: items.Total(), Captured: true},
+	}
+	input, err := app.NewReturnInput(testOrder().OrderId, order, items[1].Id, "wrong size")
+	require.NoError(t, err)
+	return env, input, inventory, payments
+}
+
+func sendReturnReceived(env *testsuite.TestWorkflowEnvironment, condition string) {
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow("ReturnReceived", messages.ReturnReceivedInput{Condition: condition})
+	}, 48*time.Hour)
+}
+
+func returnStatus(t *testing.T, env *testsuite.TestWorkflowEnvironment) messages.ReturnStatus {
+	value, err := env.QueryWorkflow("getReturnStatus")
+	require.NoError(t, err)
+	var status messages.ReturnStatus
+	require.NoError(t, value.Get(&status))
+	return status
+}
+
+func TestReturnWorkflow_RestocksAndRefunds(t *testing.T) {
+	env, input, inventory, _ := newReturnTestEnvironment(t)
+	// opened items are refunded less a restocking fee
+	env.OnActivity(activities.InspectReturn, mock.Anything, input, app.ReturnConditionOpened).
+		Return(app.Inspection{Condition: app.ReturnConditionOpened, Restock: true, RefundAmount: 3920}, nil).Once()
+	sendReturnReceived(env, app.ReturnConditionOpened)
+
+	env.ExecuteWorkflow(ReturnWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var output app.ReturnOutput
+	require.NoError(t, env.GetWorkflowResult(&output))
+	require.Equal(t, app.ReturnOutput{Status: messages.ReturnStatusRefunded, Condition: app.ReturnConditionOpened, RefundAmount: 3920, Restocked: true}, output)
+	require.Equal(t, messages.ReturnStatusRefunded, returnStatus(t, env).Status)
+
+	// the two table legs are back in stock, once
+	require.NoError(t, inventory.Reserve(context.Background(), "next", app.Items{{Sku: "TBL-LEGS", Quantity: 12}}))
+}
+
+func TestReturnWorkflow_RetriedRefundIsNotRepeated(t *testing.T) {
+	env, input, _, payments := newReturnTestEnvironment(t)
+	env.OnActivity(activities.InspectReturn, mock.Anything, input, app.ReturnConditionUnopened).
+		Return(app.Inspection{Condition: app.ReturnConditionUnopened, Restock: true, RefundAmount: 4900}, nil).Once()
+	// the first attempt refunds the customer but its result is lost, so the
+	// activity is retried with the same return's refund key
+	a := &activities.Activities{Payments: payments}
+	attempts := 0
+	env.OnActivity(a.RefundCustomer, mock.Anything, input, int64(4900)).
+		Return(func(ctx context.Context, input app.ReturnInput, amount int64) (string, error) {
+			attempts++
+			orderId, err := a.RefundCustomer(ctx, input, amount)
+			if attempts == 1 && err == nil {
+				return "", errors.New("connection reset")
+			}
+			return orderId, err
+		})
+	sendReturnReceived(env, app.ReturnConditionUnopened)
+
+	env.ExecuteWorkflow(ReturnWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, 2, attempts)
+	require.Equal(t, messages.ReturnStatusRefunded, returnStatus(t, env).Status)
+
+	// the payment was refunded once: everything but the returned item can
+	// still be refunded, and no more
+	ctx := context.Background()
+	remaining := testItems().Total() - 4900
+	require.Error(t, payments.Refund(ctx, input.Payment.AuthorizationId, "next", remaining+1))
+	require.NoError(t, payments.Refund(ctx, input.Payment.AuthorizationId, "next", remaining))
+}
+
+func TestReturnWorkflow_FailedRefundUndoesRestock(t *testing.T) {
+	env, input, inventory, _ := newReturnTestEnvironment(t)
+	env.OnActivity(activities.InspectReturn, mock.Anything, input, app.ReturnConditionUnopened).
+		Return(app.Inspection{Condition: app.ReturnConditionUnopened, Restock: true, RefundAmount: 4900}, nil).Once()
+	var a *activities.Activities
+	env.OnActivity(a.RefundCustomer, mock.Anything, input, int64(4900)).
+		Return("", temporal.NewNonRetryableApplicationError("refund customer activity failed", "paymentFailure", nil)).Once()
+	sendReturnReceived(env, app.ReturnConditionUnopened)
+
+	env.ExecuteWorkflow(ReturnWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	status := returnStatus(t, env)
+	require.Equal(t, messages.ReturnStatusFailed, status.Status)
+	require.Len(t, status.Compensations, 1)
+	require.Equal(t, "UndoRestockItem", status.Compensations[0].Activity)
+	require.Empty(t, status.Compensations[0].Error)
+
+	// the restock was undone
+	require.Error(t, inventory.Reserve(context.Background(), "next", app.Items{{Sku: "TBL-LEGS", Quantity: 11}}))
+}
+
+func TestReturnWorkflow_ExpiresWithoutItem(t *testing.T) {
+	env, input, _, _ := newReturnTestEnvironment(t)
+
+	env.ExecuteWorkflow(ReturnWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var output app.ReturnOutput
+	require.NoError(t, env.GetWorkflowResult(&output))
+	require.Equal(t, messages.ReturnStatusExpired, output.Status)
+	env.AssertActivityNotCalled(t, "InspectReturn", mock.Anything, mock.Anything, mock.Anything)
+}
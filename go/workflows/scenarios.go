@@ -0,0 +1,183 @@
+package workflows
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"temporal-order-management/app"
+
+	"go.temporal.io/sdk/workflow"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed scenarios.yaml
+var defaultScenarios []byte
+
+// ScenarioRegistry is the set of demo scenarios, defined in YAML. The built-in
+// scenarios are in scenarios.yaml.
+type ScenarioRegistry struct {
+	Scenarios []app.Scenario `yaml:"scenarios"`
+}
+
+var registry atomic.Pointer[ScenarioRegistry]
+
+func init() {
+	r, err := ParseScenarios(defaultScenarios)
+	if err != nil {
+		panic(err)
+	}
+	registry.Store(r)
+}
+
+// LoadScenarios replaces the built-in scenarios with those in SCENARIOS_FILE,
+// if set. Workers, and the clients starting orders, load the same file.
+func LoadScenarios() error {
+	path := app.GetEnv("SCENARIOS_FILE", "")
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	r, err := ParseScenarios(data)
+	if err != nil {
+		return fmt.Errorf("invalid scenarios file %v: %w", path, err)
+	}
+	SetScenarios(r)
+	return nil
+}
+
+// ParseScenarios parses and validates a YAML scenario registry.
+func ParseScenarios(data []byte) (*ScenarioRegistry, error) {
+	var r ScenarioRegistry
+	err := yaml.Unmarshal(data, &r)
+	if err != nil {
+		return nil, err
+	}
+	err = r.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// SetScenarios makes r the scenario registry.
+func SetScenarios(r *ScenarioRegistry) {
+	registry.Store(r)
+}
+
+// ListScenarios returns every scenario in the registry.
+func ListScenarios() []app.Scenario {
+	return registry.Load().Scenarios
+}
+
+// LookupScenario returns the scenario run as workflowType.
+func LookupScenario(workflowType string) (app.Scenario, bool) {
+	name, ok := strings.CutPrefix(workflowType, "OrderWorkflow")
+	if !ok {
+		return app.Scenario{}, false
+	}
+	for _, s := range registry.Load().Scenarios {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return app.Scenario{}, false
+}
+
+// ScenarioWorkflowType returns the workflow type for a scenario such as "HappyPath".
+func ScenarioWorkflowType(scenario string) (string, bool) {
+	workflowType := "OrderWorkflow" + strings.TrimPrefix(scenario, "OrderWorkflow")
+	_, ok := LookupScenario(workflowType)
+	return workflowType, ok
+}
+
+var scenarioSteps = []string{
+	"CheckFraud", "ReserveInventory", "ChargeCustomer", "PrepareShipment", "ShipOrder", "CapturePayment", app.StepWorkflow,
+}
+
+// Validate checks every scenario has a unique name and known settings.
+func (r *ScenarioRegistry) Validate() error {
+	var names []string
+	for _, s := range r.Scenarios {
+		if s.Name == "" {
+			return fmt.Errorf("scenario without a name")
+		}
+		if slices.Contains(names, s.Name) {
+			return fmt.Errorf("scenario %q defined more than once", s.Name)
+		}
+		names = append(names, s.Name)
+
+		switch s.Shipping {
+		case "", app.ShippingActivity, app.ShippingChild, app.ShippingNexus:
+		default:
+			return fmt.Errorf("scenario %q: unknown shipping %q, expected activity, child or nexus", s.Name, s.Shipping)
+		}
+		switch s.AwaitAddress {
+		case "", app.AwaitSignal, app.AwaitUpdate:
+		default:
+			return fmt.Errorf("scenario %q: unknown awaitAddress %q, expected signal or update", s.Name, s.AwaitAddress)
+		}
+		switch s.VersioningBehavior {
+		case "", "pinned", "auto-upgrade":
+		default:
+			return fmt.Errorf("scenario %q: unknown versioningBehavior %q, expected pinned or auto-upgrade", s.Name, s.VersioningBehavior)
+		}
+		if s.Failure != nil {
+			if !slices.Contains(scenarioSteps, s.Failure.Step) {
+				return fmt.Errorf("scenario %q: unknown failure step %q", s.Name, s.Failure.Step)
+			}
+			if s.Failure.Message == "" {
+				return fmt.Errorf("scenario %q: failure without a message", s.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveScenario returns the scenario of the order, looking it up by workflow
+// type if the starter did not set one, and sets it on input so activities see
+// it. Unknown workflow types run as a plain order.
+//
+// The lookup is recorded in a side effect, so the order replays with the
+// scenario it started with whatever registry the replaying worker has. Orders
+// started before the lookup was recorded still look it up on every replay.
+func resolveScenario(ctx workflow.Context, name string, input *app.OrderInput) (app.Scenario, error) {
+	if input.Scenario != nil {
+		return *input.Scenario, nil
+	}
+	lookup := func() app.Scenario {
+		scenario, ok := LookupScenario(name)
+		if !ok {
+			scenario = app.Scenario{Name: strings.TrimPrefix(name, "OrderWorkflow")}
+		}
+		return scenario
+	}
+	var scenario app.Scenario
+	if useRecordedScenario(ctx) {
+		err := workflow.SideEffect(ctx, func(workflow.Context) any { return lookup() }).Get(&scenario)
+		if err != nil {
+			return app.Scenario{}, err
+		}
+	} else {
+		scenario = lookup()
+	}
+	input.Scenario = &scenario
+	return scenario, nil
+}
+
+type scenarioContextKey struct{}
+
+// withScenario makes the scenario available to helpers through ctx.
+func withScenario(ctx workflow.Context, scenario app.Scenario) workflow.Context {
+	return workflow.WithValue(ctx, scenarioContextKey{}, scenario)
+}
+
+func scenarioFromContext(ctx workflow.Context) app.Scenario {
+	scenario, _ := ctx.Value(scenarioContextKey{}).(app.Scenario)
+	return scenario
+}
@@ -0,0 +1,88 @@
+package workflows
+
+import (
+	"temporal-order-management/app"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func TestBuiltInScenarios(t *testing.T) {
+	for _, workflowType := range []string{HAPPY, BUG, CHILD, NEXUS, SIGNAL, UPDATE, VISIBILITY, KEYROTATE, APIFAILURE, NONRECOVERABLE, WORKERCRASH, CARRIER, ENTITY, PINNED, AUTOUPGRADE} {
+		_, ok := LookupScenario(workflowType)
+		require.True(t, ok, workflowType)
+	}
+
+	workflowType, ok := ScenarioWorkflowType("ChildWorkflow")
+	require.True(t, ok)
+	require.Equal(t, CHILD, workflowType)
+	_, ok = ScenarioWorkflowType("Refund")
+	require.False(t, ok)
+}
+
+func TestParseScenariosRejectsInvalidScenarios(t *testing.T) {
+	tests := map[string]string{
+		"no name":          "scenarios:\n  - shipping: child\n",
+		"duplicate name":   "scenarios:\n  - name: A\n  - name: A\n",
+		"unknown shipping": "scenarios:\n  - name: A\n    shipping: drone\n",
+		"unknown await":    "scenarios:\n  - name: A\n    awaitAddress: email\n",
+		"unknown step":     "scenarios:\n  - name: A\n    failure: {step: Refund, message: failed}\n",
+		"no message":       "scenarios:\n  - name: A\n    failure: {step: ShipOrder}\n",
+		"unknown behavior": "scenarios:\n  - name: A\n    versioningBehavior: sometimes\n",
+		"not a scenario":   "scenarios: HappyPath\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseScenarios([]byte(data))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestScenarioFromRegistry(t *testing.T) {
+	r, err := ParseScenarios([]byte(`
+scenarios:
+  - name: TrackedChildShipping
+    shipping: child
+    searchAttributes: true
+`))
+	require.NoError(t, err)
+	SetScenarios(r)
+	t.Cleanup(func() {
+		r, _ := ParseScenarios(defaultScenarios)
+		SetScenarios(r)
+	})
+
+	var ts testsuite.WorkflowTestSuite
+	env := newTestEnvironment(&ts, nil)
+	mockActivities(env)
+	var childWorkflows int
+	env.SetOnChildWorkflowStartedListener(func(*workflow.Info, workflow.Context, converter.EncodedValues) { childWorkflows++ })
+	env.OnUpsertTypedSearchAttributes(mock.Anything).Return(nil).Times(5)
+	env.ExecuteWorkflow("OrderWorkflowTrackedChildShipping", testOrder())
+
+	require.NoError(t, env.GetWorkflowError())
+	require.Equal(t, len(testItems()), childWorkflows)
+	env.AssertExpectations(t)
+}
+
+func TestScenarioFromInput(t *testing.T) {
+	var ts testsuite.WorkflowTestSuite
+	env := newTestEnvironment(&ts, nil)
+	mockActivities(env)
+	input := testOrder()
+	input.Scenario = &app.Scenario{
+		Name:    "Custom",
+		Failure: &app.ScenarioFailure{Step: app.StepWorkflow, Message: "custom bug"},
+	}
+
+	// the input takes precedence over the registry
+	env.ExecuteWorkflow(HAPPY, input)
+
+	require.Error(t, env.GetWorkflowError())
+	require.Contains(t, env.GetWorkflowError().Error(), "custom bug")
+}
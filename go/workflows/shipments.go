@@ -0,0 +1,183 @@
+package workflows
+
+import (
+	"temporal-order-management/activities"
+	"temporal-order-management/app"
+	"temporal-order-management/messages"
+
+	"github.com/google/uuid"
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/workflow"
+)
+
+// shipShipments routes the order items to warehouses and ships one shipment
+// per warehouse with shipping, recording the outcome of each item as its
+// shipment completes. The shipments are returned with their tracking ids.
+func shipShipments(ctx workflow.Context, input app.OrderInput, items app.Items, shipping string, status *messages.OrderStatus) ([]app.Shipment, error) {
+	var a *activities.Activities
+	var shipments []app.Shipment
+	err := workflow.ExecuteActivity(ctx, a.RouteShipments, input, items).Get(ctx, &shipments)
+	if err != nil {
+		return nil, err
+	}
+
+	setItemStatus := shipmentItemStatus(items, status)
+	var shipErr error
+	selector := workflow.NewSelector(ctx)
+	for i := range shipments {
+		f := shipShipmentAsync(ctx, input, shipments[i], shipping)
+		setItemStatus(shipments[i], messages.ItemStatusShipping)
+		selector.AddFuture(f, func(f workflow.Future) {
+			err := f.Get(ctx, &shipments[i].TrackingId)
+			if err != nil {
+				setItemStatus(shipments[i], messages.ItemStatusFailed)
+				if shipErr == nil {
+					shipErr = err
+				}
+				return
+			}
+			setItemStatus(shipments[i], messages.ItemStatusShipped)
+			status.TrackingIds = append(status.TrackingIds, shipments[i].TrackingId)
+		})
+	}
+
+	for range shipments {
+		selector.Select(ctx)
+		if shipErr != nil {
+			return nil, shipErr
+		}
+	}
+	return shipments, nil
+}
+
+// awaitDeliveries waits for the carrier to confirm delivery of each shipment,
+// marking its items delivered as it is confirmed. The confirmations are
+// returned in the order of the shipments.
+func awaitDeliveries(ctx workflow.Context, input app.OrderInput, items app.Items, shipments []app.Shipment, status *messages.OrderStatus) ([]app.DeliveryConfirmation, error) {
+	var a *activities.Activities
+	setItemStatus := shipmentItemStatus(items, status)
+	deliveries := make([]app.DeliveryConfirmation, len(shipments))
+
+	var deliveryErr error
+	selector := workflow.NewSelector(ctx)
+	for i := range shipments {
+		shippingInput := app.ShippingInput{Order: input, Shipment: &shipments[i]}
+		f := workflow.ExecuteActivity(withCarrierConfirmationOptions(ctx), a.AwaitCarrierConfirmation, shippingInput)
+		selector.AddFuture(f, func(f workflow.Future) {
+			err := f.Get(ctx, &deliveries[i])
+			if err != nil {
+				if deliveryErr == nil {
+					deliveryErr = err
+				}
+				return
+			}
+			setItemStatus(shipments[i], messages.ItemStatusDelivered)
+		})
+	}
+
+	for range shipments {
+		selector.Select(ctx)
+		if deliveryErr != nil {
+			return nil, deliveryErr
+		}
+	}
+	return deliveries, nil
+}
+
+// shipmentItemStatus returns a function setting the status of every item of a
+// shipment in the order status.
+func shipmentItemStatus(items app.Items, status *messages.OrderStatus) func(shipment app.Shipment, itemStatus string) {
+	itemIndex := map[int]int{}
+	for i, item := range items {
+		itemIndex[item.Id] = i
+	}
+	return func(shipment app.Shipment, itemStatus string) {
+		for _, item := range shipment.Items {
+			status.SetItemStatus(itemIndex[item.Id], itemStatus)
+		}
+	}
+}
+
+// shipShipmentAsync ships a shipment with an activity per item, a child
+// workflow or a Nexus operation, as set by the scenario's shipping. The future
+// is the tracking id of the shipment.
+func shipShipmentAsync(ctx workflow.Context, input app.OrderInput, shipment app.Shipment, shipping string) workflow.Future {
+	logger := workflow.GetLogger(ctx)
+
+	shippingInput := app.ShippingInput{
+		Order:    input,
+		Shipment: &shipment,
+	}
+
+	if app.ShippingChild == shipping {
+		// execute an async child wf to ship the shipment
+		cwo := workflow.ChildWorkflowOptions{
+			WorkflowID:        app.ShipmentWorkflowId(input.OrderId, shipment.Id),
+			TaskQueue:         input.ShippingTaskQueue,
+			ParentClosePolicy: enums.PARENT_CLOSE_POLICY_TERMINATE,
+		}
+		ctx = workflow.WithChildOptions(ctx, cwo)
+		logger.Info("Started Child Workflow: " + cwo.WorkflowID)
+		return workflow.ExecuteChildWorkflow(ctx, ShippingWorkflow, shippingInput)
+	}
+
+	if app.ShippingNexus == shipping {
+		client := workflow.NewNexusClient(app.GetEnv("TEMPORAL_NEXUS_SHIPPING_ENDPOINT", "shipping-endpoint"), app.ShippingServiceName)
+		fut := client.ExecuteOperation(ctx, app.ShippingOperationName, shippingInput, workflow.NexusOperationOptions{})
+
+		var exec workflow.NexusOperationExecution
+		fut.GetNexusOperationExecution().Get(ctx, &exec)
+		logger.Info("Started Nexus Operation: " + exec.OperationToken)
+		return fut
+	}
+
+	// execute an async activity per item of the shipment
+	f, settable := workflow.NewFuture(ctx)
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		err := shipShipmentItems(withShipOrderOptions(ctx, input), shippingInput)
+		if err != nil {
+			settable.SetError(err)
+			return
+		}
+		settable.SetValue(newTrackingId(ctx))
+	})
+	logger.Info("Started Activities: ShipOrder", "shipment", shipment.Id)
+	return f
+}
+
+// shipShipmentItems ships every item of a shipment with ShipOrder, and
+// returns the first error once they are all done.
+func shipShipmentItems(ctx workflow.Context, input app.ShippingInput) error {
+	var futures []workflow.Future
+	for _, item := range input.Shipment.Items {
+		futures = append(futures, workflow.ExecuteActivity(ctx, activities.ShipOrder, app.ShippingInput{Order: input.Order, Item: item}))
+	}
+
+	var shipErr error
+	for _, f := range futures {
+		err := f.Get(ctx, nil)
+		if err != nil && shipErr == nil {
+			shipErr = err
+		}
+	}
+	return shipErr
+}
+
+// newTrackingId returns a tracking id, recorded in the history so the
+// workflow replays with the same id.
+func newTrackingId(ctx workflow.Context) string {
+	var trackingId string
+	workflow.SideEffect(ctx, func(ctx workflow.Context) interface{} {
+		return uuid.New().String()
+	}).Get(&trackingId)
+	return trackingId
+}
+
+// shipmentTrackingIds returns the tracking ids of shipments, in order.
+func shipmentTrackingIds(shipments []app.Shipment) []string {
+	var trackingIds []string
+	for _, shipment := range shipments {
+		trackingIds = append(trackingIds, shipment.TrackingId)
+	}
+	return trackingIds
+}
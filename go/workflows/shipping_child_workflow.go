@@ -14,7 +14,8 @@ func ShippingWorkflow(ctx workflow.Context, input app.ShippingInput) (string, er
 	logger.Info("Shipping workflow started", "orderId", input.Order.OrderId)
 
 	activityOptions := workflow.ActivityOptions{
-		StartToCloseTimeout: 5 * time.Second,
+		StartToCloseTimeout: shipOrderStartToCloseTimeout,
+		HeartbeatTimeout:    shipOrderHeartbeatTimeout,
 		RetryPolicy: &temporal.RetryPolicy{
 			InitialInterval:    1 * time.Second,
 			BackoffCoefficient: 2.0,
@@ -23,6 +24,15 @@ func ShippingWorkflow(ctx workflow.Context, input app.ShippingInput) (string, er
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
 
+	if input.Shipment != nil {
+		// Ship every item of the shipment and return its tracking id
+		err := shipShipmentItems(ctx, input)
+		if err != nil {
+			return "", err
+		}
+		return newTrackingId(ctx), nil
+	}
+
 	err := workflow.ExecuteActivity(ctx, activities.ShipOrder, input).Get(ctx, nil)
 	if err != nil {
 		return "", err
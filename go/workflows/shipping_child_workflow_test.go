@@ -0,0 +1,70 @@
+package workflows
+
+import (
+	"errors"
+	"temporal-order-management/activities"
+	"temporal-order-management/app"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestShippingWorkflow(t *testing.T) {
+	var s testsuite.WorkflowTestSuite
+	env := newTestEnvironment(&s, nil)
+	input := app.ShippingInput{Order: testOrder(), Item: testItems()[0]}
+	env.OnActivity(activities.ShipOrder, mock.Anything, input).Return(nil).Once()
+
+	env.ExecuteWorkflow(ShippingWorkflow, input)
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+}
+
+func TestShippingWorkflow_RetriesShipOrder(t *testing.T) {
+	var s testsuite.WorkflowTestSuite
+	env := newTestEnvironment(&s, nil)
+	env.OnActivity(activities.ShipOrder, mock.Anything, mock.Anything).Return(errors.New("carrier unavailable")).Twice()
+	env.OnActivity(activities.ShipOrder, mock.Anything, mock.Anything).Return(nil).Once()
+
+	env.ExecuteWorkflow(ShippingWorkflow, app.ShippingInput{Order: testOrder(), Item: testItems()[0]})
+
+	require.NoError(t, env.GetWorkflowError())
+	env.AssertExpectations(t)
+}
+
+func TestShippingWorkflow_Fails(t *testing.T) {
+	var s testsuite.WorkflowTestSuite
+	env := newTestEnvironment(&s, nil)
+	env.OnActivity(activities.ShipOrder, mock.Anything, mock.Anything).
+		Return(temporal.NewNonRetryableApplicationError("address not serviceable", "shippingFailure", nil))
+
+	env.ExecuteWorkflow(ShippingWorkflow, app.ShippingInput{Order: testOrder(), Item: testItems()[0]})
+
+	var appErr *temporal.ApplicationError
+	require.True(t, errors.As(env.GetWorkflowError(), &appErr))
+	require.Equal(t, "shippingFailure", appErr.Type())
+}
+
+func TestShippingWorkflow_ShipsShipment(t *testing.T) {
+	var s testsuite.WorkflowTestSuite
+	env := newTestEnvironment(&s, nil)
+	shipment := app.Shipment{Id: "west", Items: testItems()}
+	for _, item := range testItems() {
+		input := app.ShippingInput{Order: testOrder(), Item: item}
+		env.OnActivity(activities.ShipOrder, mock.Anything, input).Return(nil).Once()
+	}
+
+	env.ExecuteWorkflow(ShippingWorkflow, app.ShippingInput{Order: testOrder(), Shipment: &shipment})
+
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+	var trackingId string
+	require.NoError(t, env.GetWorkflowResult(&trackingId))
+	require.NotEmpty(t, trackingId)
+	env.AssertExpectations(t)
+}
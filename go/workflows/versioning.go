@@ -0,0 +1,90 @@
+package workflows
+
+import (
+	"go.temporal.io/sdk/workflow"
+)
+
+const (
+	PINNED      = "OrderWorkflowVersionPinned"
+	AUTOUPGRADE = "OrderWorkflowVersionAutoUpgrade"
+)
+
+// OrderWorkflowVersion is the version of the order workflow run by this worker,
+// 1 or 2. Workers of different versions run side by side as different builds
+// of a Worker Deployment.
+var OrderWorkflowVersion = 1
+
+// PatchRecoverableFailure enables the fix for the simulated bug of the
+// RecoverableFailure scenario, so orders stuck on the bug resume once a
+// patched worker is deployed.
+var PatchRecoverableFailure = false
+
+const (
+	// orderWorkflowV2 is the change made in version 2 of the order workflow.
+	orderWorkflowV2 = "order-workflow-v2"
+	// simulatedBugFix is the patch for the RecoverableFailure scenario.
+	simulatedBugFix = "fix-simulated-bug"
+	// splitShipments routes orders to warehouses and ships per warehouse.
+	splitShipments = "split-shipments"
+	// inventoryReservation reserves stock for OrderWorkflow orders.
+	inventoryReservation = "reserve-inventory"
+	// recordedScenario records the scenario looked up for orders started
+	// without one.
+	recordedScenario = "record-scenario"
+)
+
+// ScenarioVersioningBehavior returns the versioning behavior of the scenario
+// run as workflowType, or defaultBehavior if the scenario does not set one.
+// Orders of the VersionPinned scenario finish on the build they started on,
+// VersionAutoUpgrade orders move to the current build of the deployment.
+func ScenarioVersioningBehavior(workflowType string, defaultBehavior workflow.VersioningBehavior) workflow.VersioningBehavior {
+	scenario, _ := LookupScenario(workflowType)
+	switch scenario.VersioningBehavior {
+	case "pinned":
+		return workflow.VersioningBehaviorPinned
+	case "auto-upgrade":
+		return workflow.VersioningBehaviorAutoUpgrade
+	default:
+		return defaultBehavior
+	}
+}
+
+// useOrderWorkflowV2 reports whether the order takes the version 2 steps. An
+// order that upgraded from version 1 after passing this point replays without
+// them, so auto-upgrading orders stay deterministic.
+func useOrderWorkflowV2(ctx workflow.Context) bool {
+	if OrderWorkflowVersion < 2 {
+		return false
+	}
+	return workflow.GetVersion(ctx, orderWorkflowV2, workflow.DefaultVersion, 1) == 1
+}
+
+// simulatedBugFixed reports whether the order skips the simulated bug. Orders
+// never complete the workflow task with the bug, so a patched worker always
+// records the fix when it reaches it, including for orders already stuck.
+func simulatedBugFixed(ctx workflow.Context) bool {
+	if !PatchRecoverableFailure {
+		return false
+	}
+	return workflow.GetVersion(ctx, simulatedBugFix, workflow.DefaultVersion, 1) == 1
+}
+
+// useSplitShipments reports whether the order is shipped per warehouse. Orders
+// that started shipping item by item before the change replay without it.
+func useSplitShipments(ctx workflow.Context) bool {
+	return workflow.GetVersion(ctx, splitShipments, workflow.DefaultVersion, 1) == 1
+}
+
+// useInventoryReservation reports whether the order reserves its items, and
+// releases them if it is rolled back. Orders that passed this point before the
+// change replay without a reservation.
+func useInventoryReservation(ctx workflow.Context) bool {
+	return workflow.GetVersion(ctx, inventoryReservation, workflow.DefaultVersion, 1) == 1
+}
+
+// useRecordedScenario reports whether the scenario looked up for the order is
+// recorded in its history. Orders started before the change replay with a
+// fresh lookup.
+func useRecordedScenario(ctx workflow.Context) bool {
+	return workflow.GetVersion(ctx, recordedScenario, workflow.DefaultVersion, 1) == 1
+}
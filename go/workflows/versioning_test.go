@@ -0,0 +1,57 @@
+package workflows
+
+import (
+	"temporal-order-management/messages"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+	"go.temporal.io/sdk/workflow"
+)
+
+func executeVersionScenario(t *testing.T, workflowType string, version int) []string {
+	previous := OrderWorkflowVersion
+	OrderWorkflowVersion = version
+	t.Cleanup(func() { OrderWorkflowVersion = previous })
+
+	var ts testsuite.WorkflowTestSuite
+	env := newTestEnvironment(&ts, nil)
+	mockActivities(env)
+
+	env.ExecuteWorkflow(workflowType, testOrder())
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	value, err := env.QueryWorkflow("getOrderStatus")
+	require.NoError(t, err)
+	var status messages.OrderStatus
+	require.NoError(t, value.Get(&status))
+
+	var steps []string
+	for _, step := range status.CompletedSteps {
+		steps = append(steps, step.Name)
+	}
+	return steps
+}
+
+func TestVersionScenario_V1(t *testing.T) {
+	for _, workflowType := range []string{PINNED, AUTOUPGRADE} {
+		steps := executeVersionScenario(t, workflowType, 1)
+		require.Contains(t, steps, "Awaiting Pickup")
+		require.NotContains(t, steps, "Send Shipping Notice")
+	}
+}
+
+func TestVersionScenario_V2(t *testing.T) {
+	for _, workflowType := range []string{PINNED, AUTOUPGRADE} {
+		steps := executeVersionScenario(t, workflowType, 2)
+		require.Contains(t, steps, "Awaiting Pickup")
+		require.Contains(t, steps, "Send Shipping Notice")
+	}
+}
+
+func TestScenarioVersioningBehavior(t *testing.T) {
+	require.Equal(t, workflow.VersioningBehaviorPinned, ScenarioVersioningBehavior(PINNED, workflow.VersioningBehaviorAutoUpgrade))
+	require.Equal(t, workflow.VersioningBehaviorAutoUpgrade, ScenarioVersioningBehavior(AUTOUPGRADE, workflow.VersioningBehaviorPinned))
+	require.Equal(t, workflow.VersioningBehaviorPinned, ScenarioVersioningBehavior(HAPPY, workflow.VersioningBehaviorPinned))
+}
@@ -3,6 +3,10 @@ import uuid
 import os
 import asyncio
 import json
+import urllib.request
+import urllib.error
+from temporalio.client import WorkflowFailureError
+from temporalio.exceptions import ApplicationError
 from client import get_client
 from data import OrderInput, UpdateOrder
 
@@ -53,6 +57,10 @@ api_key = os.getenv("TEMPORAL_API_KEY")
 if api_key:
     scenarios.append("APIKeyRotation")
 
+# Worker versioning scenarios, for workers in a Worker Deployment
+if os.getenv("TEMPORAL_DEPLOYMENT_NAME"):
+    scenarios.extend(["VersionPinned", "VersionAutoUpgrade"])
+
 @app.route('/', methods=['GET', 'POST'])
 async def main_order_page():
     order_id = str(uuid.uuid4().int)[:6]
@@ -109,7 +117,7 @@ async def stream_progress():
 
                 desc = await order_workflow.describe()
                 if desc.status == 3:
-                    error_message = f"Workflow failed: order-{order_id}"
+                    error_message = await failure_message(order_workflow, order_id)
                     print(f"Error in stream_progress route: {error_message}")
                     yield f"data: {json.dumps({'error': error_message})}\n\n"
                     break
@@ -132,6 +140,21 @@ async def stream_progress():
     }
     return Response(event_stream(), headers=headers, content_type="text/event-stream")
 
+async def failure_message(order_workflow, order_id):
+    try:
+        await order_workflow.result()
+    except WorkflowFailureError as e:
+        # Walk the cause chain for a well-known application error
+        cause = e.cause
+        while cause is not None:
+            if isinstance(cause, ApplicationError) and cause.type == "OutOfStock" and cause.details:
+                sku = cause.details[0].get("sku")
+                return f"Workflow failed: order-{order_id}, item {sku} is out of stock"
+            cause = cause.cause
+    except Exception:
+        pass
+    return f"Workflow failed: order-{order_id}"
+
 @app.route('/signal', methods=['POST'])
 async def signal():
     order_id = request.args.get('order_id')
@@ -177,5 +200,30 @@ async def update():
 
     return jsonify(result=result)
 
+@app.route('/api_key', methods=['PUT'])
+async def api_key_rotation():
+    # Forward to the worker's key rotation endpoint so the shared secret never reaches the browser
+    new_key = await request.get_data()
+    url = os.getenv("TEMPORAL_API_KEY_ROTATION_URL", "http://127.0.0.1:3333")
+    headers = {}
+    secret = os.getenv("TEMPORAL_API_KEY_ROTATION_SECRET")
+    if secret:
+        headers["Authorization"] = f"Bearer {secret}"
+
+    def put_key():
+        req = urllib.request.Request(url, data=new_key, headers=headers, method="PUT")
+        with urllib.request.urlopen(req, timeout=15) as response:
+            return response.status, response.read().decode()
+
+    try:
+        status, body = await asyncio.to_thread(put_key)
+    except urllib.error.HTTPError as e:
+        status, body = e.code, e.read().decode()
+    except Exception as e:
+        print(f"Error rotating API key: {str(e)}")
+        return str(e), 502
+
+    return body, status
+
 if __name__ == '__main__':
     app.run(debug=True)